@@ -1,111 +1,347 @@
 package app
 
 import (
+	"context"
 	"fmt"
-	"time"
+	"strings"
+	"wikicrawler/internal/config"
 	"wikicrawler/internal/core/apiclient"
+	"wikicrawler/internal/core/apiclient/rawdatafetcher"
 	"wikicrawler/internal/core/cdcmanager"
-	"wikicrawler/internal/core/cdcmanager/cdc"
-	"wikicrawler/internal/core/kafkaclient"
+	"wikicrawler/internal/core/health"
 	"wikicrawler/internal/core/rawdatahandler"
 	"wikicrawler/internal/infra"
+	"wikicrawler/internal/infra/frontier"
+	"wikicrawler/internal/infra/graphstore"
 	dbclient "wikicrawler/internal/infra/postgresclient"
-	"wikicrawler/internal/infra/redisclient"
+	"wikicrawler/internal/infra/postgresclient/migrations"
+	"wikicrawler/internal/infra/postgresclient/tables"
+	"wikicrawler/internal/infra/ratelimiter"
+	"wikicrawler/internal/utils/file"
+	"wikicrawler/internal/utils/ratelimit"
+	"wikicrawler/internal/utils/retry"
 )
 
+// App wires the crawler components on demand, so every CLI command only
+// starts what it needs (e.g. the CDC relay does not need the APIClient).
 type App struct {
+	cfg         *config.Config
+	store       *infra.WikiStore
 	apiclient   *apiclient.APIClient
 	cdc         *cdcmanager.CDCManager
 	datahandler *rawdatahandler.RawDataHandler
+	limiter     *ratelimiter.RulesLimiter
+	leases      *frontier.LeaseKeeper
+	health      *health.Server
+	db          *dbclient.PostgresClient // migrate only; the store has its own connection
 }
 
-func NewWikiCrawlerApp() *App {
-	var app = &App{}
-	app.init()
-	return app
+func NewWikiCrawlerApp(cfg *config.Config) *App {
+	return &App{cfg: cfg}
 }
 
-func (a *App) Start() {
-	if err := a.cdc.Open(); err != nil {
-		fmt.Printf("[WikiCrawlerApp] Failed to open CDC: %v\n", err)
-		return
+// Store opens the Postgres backed store on first use.
+func (a *App) Store() (*infra.WikiStore, error) {
+	if a.store == nil {
+		store, err := infra.NewWikiStore(a.cfg.PostGresConfig(), a.cfg.Crawler.RawDataQueueCap)
+		if err != nil {
+			return nil, err
+		}
+		a.store = store
+	}
+	return a.store, nil
+}
+
+// Graph is the link graph of the store, for the query commands.
+func (a *App) Graph() (graphstore.GraphStore, error) {
+	store, err := a.Store()
+	if err != nil {
+		return nil, err
+	}
+	return store.Graph, nil
+}
+
+// Migrator connects to Postgres without opening the store, which would
+// migrate to the latest version on its own.
+func (a *App) Migrator() (*migrations.Migrator, error) {
+	if a.db == nil {
+		db, err := dbclient.NewPostgresClient(context.Background(), a.cfg.PostGresConfig())
+		if err != nil {
+			return nil, err
+		}
+		a.db = db
+	}
+	return migrations.NewMigrator(a.db, migrations.All)
+}
+
+// Start runs the CDC relay and the crawler in one process. The crawler only
+// starts once the replication slot streams, so none of its writes are missed.
+func (a *App) Start() error {
+	if err := a.StartCDC(); err != nil {
+		return err
+	}
+	if !a.cdc.Ready() {
+		return fmt.Errorf("[WikiCrawlerApp] CDC opened but is not streaming")
+	}
+	return a.StartCrawler()
+}
+
+// StartCrawler fetches titles from the seed file onward and stores the link graph.
+func (a *App) StartCrawler() error {
+	if err := a.initCrawler(); err != nil {
+		fmt.Printf("[WikiCrawlerApp] Failed to init crawler: %v\n", err)
+		return err
+	}
+
+	if err := a.leases.Start(); err != nil {
+		fmt.Printf("[WikiCrawlerApp] Failed to start lease keeper: %v\n", err)
+		return err
+	}
+
+	if a.limiter != nil {
+		if err := a.limiter.Start(); err != nil {
+			fmt.Printf("[WikiCrawlerApp] Failed to start rate limiter: %v\n", err)
+			return err
+		}
+	}
+
+	// The handler first: the fetchers hand it pages as soon as they start
+	if err := a.datahandler.Start(); err != nil {
+		fmt.Printf("[WikiCrawlerApp] Failed to start rawdatahandler: %v\n", err)
+		return err
 	} else {
-		fmt.Printf("[WikiCrawlerApp] CDC opened successfully\n")
+		fmt.Printf("[WikiCrawlerApp] rawdatahandler started successfully\n")
 	}
-	time.Sleep(2 * time.Second)
 
 	if err := a.apiclient.Start(); err != nil {
 		fmt.Printf("[WikiCrawlerApp] Failed to start apiclient: %v\n", err)
-		return
+		return err
 	} else {
 		fmt.Printf("[WikiCrawlerApp] apiclient started successfully\n")
 	}
 
-	time.Sleep(2 * time.Second)
-	if err := a.datahandler.Start(); err != nil {
-		fmt.Printf("[WikiCrawlerApp] Failed to start rawdatahandler: %v\n", err)
-		return
-	} else {
-		fmt.Printf("[WikiCrawlerApp] rawdatahandler started successfully\n")
+	if a.cfg.Health.Addr != "" {
+		a.health = health.NewServer(a.cfg.Health.Addr)
+		a.registerHealthChecks()
+		if err := a.health.Start(); err != nil {
+			fmt.Printf("[WikiCrawlerApp] Failed to start health server: %v\n", err)
+			return err
+		}
 	}
+	return nil
+}
 
+// registerHealthChecks reports the circuit breaker (unhealthy while open), the
+// fetcher pool and the handler backlog (unhealthy once a page was dropped).
+func (a *App) registerHealthChecks() {
+	if b := a.apiclient.Breaker(); b != nil {
+		a.health.Register("breaker", func() (bool, any) {
+			s := b.Status()
+			return s.State != rawdatafetcher.BreakerOpen, s
+		})
+	}
+	a.health.Register("handler", func() (bool, any) {
+		s := a.datahandler.Stats()
+		return s.Dropped == 0, s
+	})
+	a.health.Register("fetchers", func() (bool, any) {
+		return true, map[string]any{
+			"concurrency_limit": a.apiclient.ConcurrencyLimit(),
+			"workers":           a.apiclient.Stats(),
+		}
+	})
+}
+
+// StartCDC relays titles/pairs changes from the replication slot to Kafka.
+func (a *App) StartCDC() error {
+	a.initCDC()
+
+	if err := a.cdc.Open(); err != nil {
+		fmt.Printf("[WikiCrawlerApp] Failed to open CDC: %v\n", err)
+		return err
+	} else {
+		fmt.Printf("[WikiCrawlerApp] CDC opened successfully\n")
+	}
+	return nil
 }
 
 func (a *App) Stop() {
-	if err := a.apiclient.Stop(); err != nil {
-		fmt.Printf("[WikiCrawlerApp] Failed to stop apiclient: %v\n", err)
+	if a.health != nil {
+		if err := a.health.Stop(); err != nil {
+			fmt.Printf("[WikiCrawlerApp] Failed to stop health server: %v\n", err)
+		}
+	}
+	if a.apiclient != nil {
+		if err := a.apiclient.Stop(); err != nil {
+			fmt.Printf("[WikiCrawlerApp] Failed to stop apiclient: %v\n", err)
+		}
+	}
+	if a.limiter != nil {
+		if err := a.limiter.Stop(); err != nil {
+			fmt.Printf("[WikiCrawlerApp] Failed to stop rate limiter: %v\n", err)
+		}
+	}
+	if a.leases != nil {
+		if err := a.leases.Stop(); err != nil {
+			fmt.Printf("[WikiCrawlerApp] Failed to stop lease keeper: %v\n", err)
+		}
+	}
+	if a.cdc != nil {
+		if err := a.cdc.Close(); err != nil {
+			fmt.Printf("[WikiCrawlerApp] Failed to close CDC: %v\n", err)
+		}
 	}
-	if err := a.cdc.Close(); err != nil {
-		fmt.Printf("[WikiCrawlerApp] Failed to close CDC: %v\n", err)
+	if a.datahandler != nil {
+		if err := a.datahandler.Stop(); err != nil {
+			fmt.Printf("[WikiCrawlerApp] Failed to stop rawdatahandler: %v\n", err)
+		}
 	}
-	if err := a.datahandler.Stop(); err != nil {
-		fmt.Printf("[WikiCrawlerApp] Failed to stop rawdatahandler: %v\n", err)
+	if a.store != nil {
+		a.store.Close()
+	}
+	if a.db != nil {
+		a.db.Close()
 	}
 }
 
+// Wiki returns wiki, or the configured default wiki when it is empty.
+func (a *App) Wiki(wiki string) string {
+	if wiki == "" {
+		return a.cfg.Crawler.Wiki
+	}
+	return wiki
+}
+
+// Frontier opens the configured crawl frontier on first use.
+func (a *App) Frontier() (frontier.Frontier, error) {
+	store, err := a.Store()
+	if err != nil {
+		return nil, err
+	}
+	if store.Frontier == nil {
+		if a.cfg.Frontier.Backend == "redis" && store.RedisClient == nil {
+			store.ConnectRedis(a.cfg.RedisConfig())
+		}
+		if err := store.OpenFrontier(a.cfg.Frontier.Backend, a.cfg.Frontier.LeaseTimeout); err != nil {
+			return nil, err
+		}
+	}
+	return store.Frontier, nil
+}
+
+// AddSeeds pushes titles to the frontier, so a running crawler picks them up,
+// and appends the ones that are not yet in the seed file for fresh databases.
+func (a *App) AddSeeds(titles []string) (int, error) {
+	if _, err := a.Frontier(); err != nil {
+		return 0, err
+	}
+	queued, err := a.store.PushSeeds(titles, a.cfg.Crawler.Wiki)
+	if err != nil {
+		return 0, err
+	}
+
+	existing := map[string]bool{}
+	if lines, err := file.ReadTextFile(a.cfg.Crawler.SeedFile); err == nil {
+		for _, l := range lines {
+			existing[strings.TrimSpace(l)] = true
+		}
+	}
+
+	var added []string
+	for _, t := range titles {
+		t = strings.TrimSpace(t)
+		if t == "" || existing[t] {
+			continue
+		}
+		existing[t] = true
+		added = append(added, t)
+	}
+	if len(added) > 0 {
+		if err := file.AppendLines(a.cfg.Crawler.SeedFile, added); err != nil {
+			return queued, err
+		}
+	}
+	return queued, nil
+}
+
 // ///////////////////////////////////////////////////////////////////////////////////////
-func (a *App) init() {
-	datapath := "./data/seed_names.txt"
-	cfg := dbclient.PostGresConfig{
-		Host:     "localhost", // IP
-		Port:     "5432",      // Port
-		User:     "erduser",   // user_name
-		Password: "erdp@ss",   // password
-		DBname:   "wikidb",    // db
-	}
-	rcfg := redisclient.RedisConfig{
-		Addr:     "localhost:6379", // redis server host
-		Password: "",
-		DB:       2, // Logical database index (Redis has 16 by default: 0–15).
-	}
-
-	RawDataQCap := 1000
-	TitlsToQueryQCap := 1000
-	store := infra.NewWikiStore(datapath, cfg, rcfg, RawDataQCap, TitlsToQueryQCap)
-	a.apiclient = apiclient.NewAPIClient(store, 30*time.Second, 90*time.Second, 10000, 10)
-
-	cfgcd := &cdc.CDCConfig{
-		Replicator:       "replicator",
-		Psw:              "erdp@ss",
-		Address:          "localhost:5432",
-		DB:               "wikidb",
-		Replication_slot: "wikidb_slot",
-		OutputPlugin:     "pgoutput",
-		Publication:      "wikidb_pub",
-	}
-	cfgrelpro := kafkaclient.KafkaConfig{
-		BootstrapServers: "localhost:19092",
-		Topic:            store.PairsTable.TableName,
-	}
-	cfgentpro := kafkaclient.KafkaConfig{
-		BootstrapServers: "localhost:19092",
-		Topic:            store.TitlesTable.TableName,
-	}
-	relTBname := store.PairsTable.TableName
-	entTBname := store.TitlesTable.TableName
-	a.cdc = cdcmanager.NewCDCManager(cfgcd, cfgrelpro, cfgentpro, relTBname, entTBname)
-
-	a.datahandler = rawdatahandler.NewRawDataHandler(store, 10, 100)
-
-	fmt.Printf("[WikiCrawlerApp] done to init all components!\n")
+func (a *App) initCrawler() error {
+	store, err := a.Store()
+	if err != nil {
+		return err
+	}
+	if store.RedisClient == nil {
+		store.ConnectRedis(a.cfg.RedisConfig())
+	}
+	if _, err := a.Frontier(); err != nil {
+		return err
+	}
+	store.LoadSeeds(a.cfg.Crawler.SeedFile, a.cfg.Crawler.Wiki)
+	// Leases of this process are renewed until the handler stored their pages,
+	// so retries and a busy handler never let a live batch be leased again
+	a.leases = frontier.NewLeaseKeeper(store.Frontier, a.cfg.Frontier.LeaseTimeout/3)
+	store.Frontier = a.leases
+
+	// A replayed crawl never reaches Wikipedia, so it does not need to be polite
+	replay := a.cfg.Crawler.ArchiveMode == "replay"
+	rate := a.cfg.Crawler.RateLimit
+	if replay {
+		rate = 0
+	}
+
+	a.apiclient = apiclient.NewAPIClient(store, a.cfg.Crawler.Timeout, a.cfg.Crawler.IdleConnTimeout,
+		a.cfg.Crawler.MaxIdleConns, a.cfg.Crawler.MaxIdleConnsPerHost, a.cfg.Frontier.PollInterval, a.cfg.Crawler.Wiki,
+		a.cfg.Crawler.BatchSize, a.cfg.Crawler.FetchWorkers,
+		ratelimit.NewTokenBucket(float64(rate), a.cfg.Crawler.RateBurst),
+		retry.Exponential{
+			Base:        a.cfg.Crawler.RetryBase,
+			Max:         a.cfg.Crawler.RetryMax,
+			MaxAttempts: a.cfg.Crawler.MaxAttempts,
+			Jitter:      0.5,
+		},
+		a.cfg.Crawler.Maxlag, a.cfg.Crawler.StatsInterval)
+	if a.cfg.Crawler.MinConcurrency > 0 {
+		a.apiclient.SetConcurrency(rawdatafetcher.NewAIMD(a.cfg.Crawler.MinConcurrency, a.cfg.Crawler.FetchWorkers,
+			a.cfg.Crawler.LatencyTarget))
+	}
+	if a.cfg.Crawler.BreakerThreshold > 0 {
+		a.apiclient.SetBreaker(rawdatafetcher.NewBreaker(a.cfg.Crawler.BreakerThreshold, a.cfg.Crawler.BreakerCooldown))
+	}
+	switch a.cfg.Crawler.ArchiveMode {
+	case "record":
+		if err := a.apiclient.Record(a.cfg.Crawler.ArchiveFile); err != nil {
+			return err
+		}
+	case "replay":
+		if err := a.apiclient.Replay(a.cfg.Crawler.ArchiveFile); err != nil {
+			return err
+		}
+	}
+	if a.cfg.Limiter.ReloadInterval > 0 && !replay {
+		limiter, err := ratelimiter.NewRulesLimiter(store.DBclient, store.RedisClient, a.cfg.Limiter.KeyPrefix,
+			a.cfg.Limiter.ReloadInterval)
+		if err != nil {
+			return err
+		}
+		a.limiter = limiter
+		a.apiclient.SetLimiter(limiter)
+	}
+	handler, err := rawdatahandler.NewRawDataHandler(store.Graph, store.RawDataQ, store.Frontier, a.cfg.Handler.Workers, a.cfg.Handler.TaskQueueCap,
+		a.cfg.Crawler.MaxDepth, a.cfg.Handler.PushTimeout, a.cfg.Handler.SpillFile)
+	if err != nil {
+		return err
+	}
+	a.datahandler = handler
+
+	fmt.Printf("[WikiCrawlerApp] done to init crawler components!\n")
+	return nil
+}
+
+func (a *App) initCDC() {
+	relTBname := tables.PairsTableName
+	entTBname := tables.TitlesTableName
+	a.cdc = cdcmanager.NewCDCManager(a.cfg.CDCConfig(), a.cfg.KafkaConfig(relTBname), a.cfg.KafkaConfig(entTBname),
+		relTBname, entTBname)
+
+	fmt.Printf("[WikiCrawlerApp] done to init CDC components!\n")
 }
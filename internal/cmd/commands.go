@@ -0,0 +1,315 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"wikicrawler/internal/app"
+	"wikicrawler/internal/config"
+	"wikicrawler/internal/core/graphquery"
+	"wikicrawler/internal/fakewiki"
+	"wikicrawler/internal/infra/frontier"
+)
+
+// load parses config + command flags and returns the App and positional args.
+func load(fs *flag.FlagSet, args []string) (*app.App, []string, error) {
+	cfg, err := config.Load(fs, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	return app.NewWikiCrawlerApp(cfg), fs.Args(), nil
+}
+
+func runCrawl(args []string) error {
+	a, _, err := load(flag.NewFlagSet("crawl", flag.ExitOnError), args)
+	if err != nil {
+		return err
+	}
+
+	if err := a.StartCrawler(); err != nil {
+		a.Stop()
+		return err
+	}
+	log.Println("🚀 WikiCrawler crawl is running...")
+
+	waitForSignal()
+	log.Println("⚠️ Shutting down WikiCrawler crawl...")
+	a.Stop()
+	return nil
+}
+
+func runCDC(args []string) error {
+	a, _, err := load(flag.NewFlagSet("cdc", flag.ExitOnError), args)
+	if err != nil {
+		return err
+	}
+
+	if err := a.StartCDC(); err != nil {
+		a.Stop()
+		return err
+	}
+	log.Println("🚀 WikiCrawler CDC relay is running...")
+
+	waitForSignal()
+	log.Println("⚠️ Shutting down WikiCrawler CDC relay...")
+	a.Stop()
+	return nil
+}
+
+func runAll(args []string) error {
+	a, _, err := load(flag.NewFlagSet("run", flag.ExitOnError), args)
+	if err != nil {
+		return err
+	}
+
+	if err := a.Start(); err != nil {
+		a.Stop()
+		return err
+	}
+	log.Println("🚀 WikiCrawlerApp is running...")
+
+	waitForSignal()
+	log.Println("⚠️ Shutting down WikiCrawlerApp...")
+	a.Stop()
+	return nil
+}
+
+func runSeed(args []string) error {
+	if len(args) == 0 || args[0] != "add" {
+		return fmt.Errorf("usage: wikicrawler seed add [flags] <title>...")
+	}
+	a, titles, err := load(flag.NewFlagSet("seed add", flag.ExitOnError), args[1:])
+	if err != nil {
+		return err
+	}
+	if len(titles) == 0 {
+		return fmt.Errorf("usage: wikicrawler seed add [flags] <title>...")
+	}
+
+	defer a.Stop()
+
+	n, err := a.AddSeeds(titles)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("queued %d new of %d seed title(s)\n", n, len(titles))
+	return nil
+}
+
+func runStats(args []string) error {
+	a, _, err := load(flag.NewFlagSet("stats", flag.ExitOnError), args)
+	if err != nil {
+		return err
+	}
+	defer a.Stop()
+
+	g, err := a.Graph()
+	if err != nil {
+		return err
+	}
+	s, err := graphquery.GetStats(g)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("titles: %d (%d missing, %d invalid)\npairs:  %d\n", s.Titles, s.Missing, s.Invalid, s.Pairs)
+
+	f, err := a.Frontier()
+	if err != nil {
+		return err
+	}
+	counts, err := f.Counts()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("frontier: %d pending, %d in flight, %d done, %d failed\n",
+		counts[frontier.StatePending], counts[frontier.StateInFlight], counts[frontier.StateDone],
+		counts[frontier.StateFailed])
+	return nil
+}
+
+func runPath(args []string) error {
+	fs := flag.NewFlagSet("path", flag.ExitOnError)
+	maxHops := fs.Int("max-hops", 6, "give up after this many hops")
+	wiki := fs.String("wiki", "", "wiki of both titles (default crawler.wiki)")
+	a, pos, err := load(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 2 {
+		return fmt.Errorf("usage: wikicrawler path [flags] <src> <dst>")
+	}
+	defer a.Stop()
+
+	g, err := a.Graph()
+	if err != nil {
+		return err
+	}
+	path, err := graphquery.ShortestPath(g, a.Wiki(*wiki), pos[0], pos[1], *maxHops)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s (%d hops)\n", strings.Join(path, " → "), len(path)-1)
+	return nil
+}
+
+func runWithin(args []string) error {
+	fs := flag.NewFlagSet("within", flag.ExitOnError)
+	hops := fs.Int("hops", 2, "maximum distance from the seed")
+	wiki := fs.String("wiki", "", "wiki of the seed (default crawler.wiki)")
+	a, pos, err := load(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return fmt.Errorf("usage: wikicrawler within [flags] <seed>")
+	}
+	defer a.Stop()
+
+	g, err := a.Graph()
+	if err != nil {
+		return err
+	}
+	names, err := graphquery.WithinHops(g, a.Wiki(*wiki), pos[0], *hops)
+	if err != nil {
+		return err
+	}
+	for _, n := range names {
+		fmt.Println(n)
+	}
+	return nil
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "csv", "output format: csv or jsonl")
+	out := fs.String("o", "-", "output file, - for stdout")
+	a, _, err := load(fs, args)
+	if err != nil {
+		return err
+	}
+	defer a.Stop()
+
+	w := os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	g, err := a.Graph()
+	if err != nil {
+		return err
+	}
+	n, err := graphquery.Export(g, w, *format)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "exported %d edge(s)\n", n)
+	return nil
+}
+
+func runMigrate(args []string) error {
+	a, rest, err := load(flag.NewFlagSet("migrate", flag.ExitOnError), args)
+	if err != nil {
+		return err
+	}
+	defer a.Stop()
+
+	m, err := a.Migrator()
+	if err != nil {
+		return err
+	}
+
+	action, target := "up", 0
+	if len(rest) > 0 {
+		action = rest[0]
+	}
+	if len(rest) > 1 {
+		if target, err = strconv.Atoi(rest[1]); err != nil || target < 0 {
+			return fmt.Errorf("bad version %q", rest[1])
+		}
+	}
+
+	switch action {
+	case "up":
+		done, err := m.Up(target)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("applied %d migration(s)\n", len(done))
+	case "down":
+		if len(rest) < 2 {
+			return fmt.Errorf("usage: wikicrawler migrate down <version>")
+		}
+		done, err := m.Down(target)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("rolled back %d migration(s)\n", len(done))
+	case "status":
+		statuses, err := m.Status()
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%4d  %-25s  %s\n", s.Version, applied, s.Name)
+		}
+	default:
+		return fmt.Errorf("unknown migrate action %q, want up, down or status", action)
+	}
+	return nil
+}
+
+func runFakeWiki(args []string) error {
+	fs := flag.NewFlagSet("fakewiki", flag.ExitOnError)
+	addr := fs.String("addr", ":8090", "listen address")
+	fixture := fs.String("graph", "", `JSON graph {"pages": {"A": ["B"]}, "redirects": {"Bee": "B"}}; empty = generate one`)
+	pages := fs.Int("pages", 10000, "pages of the generated graph")
+	degree := fs.Int("degree", 20, "links per generated page")
+	seed := fs.Uint64("seed", 1, "random seed of the generated graph and the faults")
+	var faults fakewiki.Faults
+	fs.Float64Var(&faults.RateLimited, "fault-429", 0, "odds of a 429 with Retry-After")
+	fs.Float64Var(&faults.ServerError, "fault-5xx", 0, "odds of a 503")
+	fs.Float64Var(&faults.Maxlag, "fault-maxlag", 0, "odds of a maxlag error when the request sets maxlag")
+	fs.Float64Var(&faults.Malformed, "fault-malformed", 0, "odds of a truncated JSON body")
+	fs.Float64Var(&faults.Slow, "fault-slow", 0, "odds of a slow answer")
+	fs.DurationVar(&faults.SlowDelay, "slow-delay", 2*time.Second, "delay of a slow answer")
+	fs.Parse(args)
+
+	graph := fakewiki.Generate(*pages, *degree, *seed)
+	if *fixture != "" {
+		g, err := fakewiki.LoadGraph(*fixture)
+		if err != nil {
+			return err
+		}
+		graph = g
+	}
+
+	srv := &http.Server{Addr: *addr, Handler: fakewiki.NewServer(graph, faults, *seed), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("❌ fakewiki: %v", err)
+		}
+	}()
+	host := *addr
+	if strings.HasPrefix(host, ":") {
+		host = "localhost" + host
+	}
+	log.Printf("🚀 fake MediaWiki with %d pages on %s, crawl it with -crawler.wiki=http://%s/w/api.php",
+		len(graph.Pages), *addr, host)
+
+	waitForSignal()
+	return srv.Close()
+}
@@ -1,26 +1,67 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
-	"wikicrawler/internal/app"
 )
 
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"crawl", "crawl [flags]", "crawl Wikipedia from the seed file and store the link graph", runCrawl},
+	{"cdc", "cdc [flags]", "relay titles/pairs changes from Postgres to Kafka", runCDC},
+	{"run", "run [flags]", "crawl and relay CDC in one process", runAll},
+	{"seed", "seed add [flags] <title>...", "queue titles in the frontier and append them to the seed file", runSeed},
+	{"stats", "stats [flags]", "print node and edge counts", runStats},
+	{"path", "path [flags] <src> <dst>", "print the shortest link path between two titles", runPath},
+	{"within", "within [flags] <seed>", "list titles crawled from a seed within -hops links", runWithin},
+	{"export", "export [flags]", "dump every edge as CSV or JSON lines", runExport},
+	{"migrate", "migrate [flags] [up|down|status] [v]", "apply or roll back schema migrations", runMigrate},
+	{"fakewiki", "fakewiki [flags]", "serve a fake MediaWiki links API for offline crawls and load tests", runFakeWiki},
+}
+
 func main() {
-	// 1. Create the WikiCrawlerApp
-	app := app.NewWikiCrawlerApp()
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
 
-	// 2. Start the app (starts workers + HTTP server)
-	go app.Start()
-	log.Println("🚀 WikiCrawlerApp is running...")
+	name := os.Args[1]
+	for _, c := range commands {
+		if c.name == name {
+			if err := c.run(os.Args[2:]); err != nil {
+				log.Fatalf("❌ %s: %v", name, err)
+			}
+			return
+		}
+	}
 
-	// 3. Graceful shutdown on SIGINT/SIGTERM
+	if name != "help" && name != "-h" && name != "--help" {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	}
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: wikicrawler <command> [flags]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-30s %s\n", c.usage, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun 'wikicrawler <command> -h' for the flags of a command.\n")
+}
+
+// waitForSignal blocks until SIGINT/SIGTERM for long running commands.
+func waitForSignal() {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
-
 	<-stop
-	log.Println("⚠️ Shutting down WikiCrawlerApp...")
-	app.Stop()
 }
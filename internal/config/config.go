@@ -0,0 +1,594 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"wikicrawler/internal/core/cdcmanager/cdc"
+	"wikicrawler/internal/core/kafkaclient"
+	dbclient "wikicrawler/internal/infra/postgresclient"
+	"wikicrawler/internal/infra/redisclient"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is prepended to every environment override, e.g. WIKICRAWLER_POSTGRES_HOST.
+const EnvPrefix = "WIKICRAWLER_"
+
+// ConfigEnv may point to the config file when no -config flag is given.
+const ConfigEnv = EnvPrefix + "CONFIG"
+
+type Config struct {
+	Postgres PostgresSection `yaml:"postgres"`
+	Redis    RedisSection    `yaml:"redis"`
+	CDC      CDCSection      `yaml:"cdc"`
+	Kafka    KafkaSection    `yaml:"kafka"`
+	Crawler  CrawlerSection  `yaml:"crawler"`
+	Frontier FrontierSection `yaml:"frontier"`
+	Handler  HandlerSection  `yaml:"handler"`
+	Limiter  LimiterSection  `yaml:"rate_limiter"`
+	Health   HealthSection   `yaml:"health"`
+}
+
+type PostgresSection struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	DBname   string `yaml:"dbname"`
+
+	MaxConns        int           `yaml:"max_conns"`          // connections in the pool per process
+	MinConns        int           `yaml:"min_conns"`          // connections kept open while idle
+	MaxConnLifetime time.Duration `yaml:"max_conn_lifetime"`  // recycle older connections
+	MaxConnIdleTime time.Duration `yaml:"max_conn_idle_time"` // close connections idle for longer
+	ConnectTimeout  time.Duration `yaml:"connect_timeout"`    // per connection attempt
+	ConnectAttempts int           `yaml:"connect_attempts"`   // tries at startup, backing off 1s, 2s, 4s, ...
+}
+
+type RedisSection struct {
+	Addr     string `yaml:"addr"`
+	Password string `yaml:"password"`
+	DB       int    `yaml:"db"` // Logical database index (Redis has 16 by default: 0–15).
+}
+
+type CDCSection struct {
+	Replicator   string `yaml:"replicator"`
+	Password     string `yaml:"password"`
+	Address      string `yaml:"address"` // defaults to postgres host:port
+	DB           string `yaml:"db"`      // defaults to postgres dbname
+	Slot         string `yaml:"slot"`
+	OutputPlugin string `yaml:"output_plugin"`
+	Publication  string `yaml:"publication"`
+}
+
+type KafkaSection struct {
+	BootstrapServers string            `yaml:"bootstrap_servers"`
+	ExtraConfig      map[string]string `yaml:"extra"`
+}
+
+type CrawlerSection struct {
+	SeedFile            string        `yaml:"seed_file"`
+	Wiki                string        `yaml:"wiki"` // wiki of seeds without a "xx:" prefix: language code, host or api.php URL
+	RawDataQueueCap     int           `yaml:"raw_data_queue_cap"`
+	Timeout             time.Duration `yaml:"timeout"`
+	IdleConnTimeout     time.Duration `yaml:"idle_conn_timeout"`
+	MaxIdleConns        int           `yaml:"max_idle_conns"`
+	MaxIdleConnsPerHost int           `yaml:"max_idle_conns_per_host"`
+	MaxDepth            int           `yaml:"max_depth"`         // deepest hop that is still crawled, 0 = unlimited
+	BatchSize           int           `yaml:"batch_size"`        // titles per MediaWiki query, 1..50
+	FetchWorkers        int           `yaml:"fetch_workers"`     // concurrent fetchers sharing the frontier
+	RateLimit           int           `yaml:"rate_limit"`        // requests per second over all fetchers, 0 = unlimited
+	RateBurst           int           `yaml:"rate_burst"`        // requests allowed at once after an idle period
+	StatsInterval       time.Duration `yaml:"stats_interval"`    // how often per-fetcher stats are logged, 0 = never
+	MaxAttempts         int           `yaml:"max_attempts"`      // tries per request before its titles are failed
+	RetryBase           time.Duration `yaml:"retry_base"`        // first backoff, doubled on every retry
+	RetryMax            time.Duration `yaml:"retry_max"`         // longest backoff, unless Retry-After asks for more
+	Maxlag              int           `yaml:"maxlag"`            // MediaWiki maxlag parameter in seconds, 0 = not sent
+	MinConcurrency      int           `yaml:"min_concurrency"`   // floor of the adaptive in-flight limit, 0 = fixed at fetch_workers
+	LatencyTarget       time.Duration `yaml:"latency_target"`    // slower responses lower the limit, 0 = only 429/503 do
+	BreakerThreshold    int           `yaml:"breaker_threshold"` // failed requests in a row that open the breaker, 0 = no breaker
+	BreakerCooldown     time.Duration `yaml:"breaker_cooldown"`  // pause before a probe request while open
+	ArchiveMode         string        `yaml:"archive_mode"`      // "" (live), "record" or "replay"
+	ArchiveFile         string        `yaml:"archive_file"`      // gzip JSON lines of recorded responses
+}
+
+type FrontierSection struct {
+	Backend      string        `yaml:"backend"`       // postgres or redis
+	LeaseTimeout time.Duration `yaml:"lease_timeout"` // renewed while in flight; a crashed crawler's titles are retried after this
+	PollInterval time.Duration `yaml:"poll_interval"` // wait when nothing is pending
+}
+
+type HandlerSection struct {
+	Workers      int           `yaml:"workers"`
+	TaskQueueCap int           `yaml:"task_queue_cap"`
+	PushTimeout  time.Duration `yaml:"push_timeout"` // wait for a free worker, 0 = as long as it takes
+	SpillFile    string        `yaml:"spill_file"`   // pages the workers cannot take in time, "" = drop them
+}
+
+// LimiterSection configures the limits read from the rate_limiter_rules table,
+// shared by every crawler process through Redis.
+type LimiterSection struct {
+	ReloadInterval time.Duration `yaml:"reload_interval"` // how often the table is checked for changes, 0 = rules off
+	KeyPrefix      string        `yaml:"key_prefix"`      // Redis keys of the token buckets
+}
+
+type HealthSection struct {
+	Addr string `yaml:"addr"` // listen address of GET /healthz, "" = no health server
+}
+
+// Default returns the settings the crawler used before it was configurable.
+func Default() *Config {
+	return &Config{
+		Postgres: PostgresSection{
+			Host:   "localhost",
+			Port:   "5432",
+			User:   "erduser",
+			DBname: "wikidb",
+
+			MaxConns:        10,
+			MaxConnLifetime: time.Hour,
+			MaxConnIdleTime: 30 * time.Minute,
+			ConnectTimeout:  5 * time.Second,
+			ConnectAttempts: 5,
+		},
+		Redis: RedisSection{
+			Addr: "localhost:6379",
+			DB:   2,
+		},
+		CDC: CDCSection{
+			Replicator:   "replicator",
+			Slot:         "wikidb_slot",
+			OutputPlugin: "pgoutput",
+			Publication:  "wikidb_pub",
+		},
+		Kafka: KafkaSection{
+			BootstrapServers: "localhost:19092",
+		},
+		Crawler: CrawlerSection{
+			SeedFile:            "./data/seed_names.txt",
+			Wiki:                "vi",
+			RawDataQueueCap:     1000,
+			Timeout:             30 * time.Second,
+			IdleConnTimeout:     90 * time.Second,
+			MaxIdleConns:        10000,
+			MaxIdleConnsPerHost: 10,
+			BatchSize:           50,
+			FetchWorkers:        4,
+			RateLimit:           10,
+			RateBurst:           10,
+			StatsInterval:       time.Minute,
+			MaxAttempts:         5,
+			RetryBase:           time.Second,
+			RetryMax:            time.Minute,
+			Maxlag:              5,
+			MinConcurrency:      1,
+			LatencyTarget:       2 * time.Second,
+			BreakerThreshold:    5,
+			BreakerCooldown:     30 * time.Second,
+			ArchiveFile:         "./data/responses.jsonl.gz",
+		},
+		Frontier: FrontierSection{
+			Backend:      "postgres",
+			LeaseTimeout: 5 * time.Minute,
+			PollInterval: time.Second,
+		},
+		Handler: HandlerSection{
+			Workers:      10,
+			TaskQueueCap: 100,
+		},
+		Limiter: LimiterSection{
+			ReloadInterval: 30 * time.Second,
+			KeyPrefix:      "wikicrawler:ratelimit",
+		},
+		Health: HealthSection{
+			Addr: ":8081",
+		},
+	}
+}
+
+// Load builds the configuration in layers: defaults, then the YAML or TOML file given by
+// -config (or WIKICRAWLER_CONFIG), then WIKICRAWLER_* environment variables, then
+// the remaining command line flags. The flag set may already carry command specific
+// flags; its positional arguments are available through fs.Args() afterwards.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	cfg := Default()
+
+	path := lookupConfigPath(fs, args)
+	if path == "" {
+		path = os.Getenv(ConfigEnv)
+	}
+	if path != "" {
+		if err := cfg.LoadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.ApplyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	fs.String("config", path, "path to a YAML or .toml config file (env "+ConfigEnv+")")
+	cfg.BindFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadFile overlays the values found in a YAML file, or a TOML file when path
+// ends in .toml, on top of cfg. Both use the same keys.
+func (c *Config) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("[Config] failed to read %s: %w", path, err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		var tree map[string]any
+		if _, err := toml.Decode(string(data), &tree); err != nil {
+			return fmt.Errorf("[Config] failed to parse %s: %w", path, err)
+		}
+		// Decoded through YAML, so both formats share the yaml tags and the typo check
+		if data, err = yaml.Marshal(tree); err != nil {
+			return fmt.Errorf("[Config] failed to parse %s: %w", path, err)
+		}
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true) // typos in keys should not be silently ignored
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("[Config] failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// ApplyEnv overlays WIKICRAWLER_* variables on top of cfg.
+func (c *Config) ApplyEnv(lookup func(string) (string, bool)) error {
+	var errs []error
+	for _, o := range c.options() {
+		raw, ok := lookup(o.env())
+		if !ok {
+			continue
+		}
+		if err := o.set(raw); err != nil {
+			errs = append(errs, fmt.Errorf("[Config] invalid %s=%q: %w", o.env(), raw, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// BindFlags registers one flag per option, named after its YAML key
+// (e.g. -postgres.host). Current values become the flag defaults.
+func (c *Config) BindFlags(fs *flag.FlagSet) {
+	for _, o := range c.options() {
+		switch p := o.ptr.(type) {
+		case *string:
+			fs.StringVar(p, o.key, *p, o.usage)
+		case *int:
+			fs.IntVar(p, o.key, *p, o.usage)
+		case *time.Duration:
+			fs.DurationVar(p, o.key, *p, o.usage)
+		}
+	}
+}
+
+// Validate checks that every setting is usable and fills in derived defaults.
+func (c *Config) Validate() error {
+	if c.CDC.Address == "" {
+		c.CDC.Address = c.Postgres.Host + ":" + c.Postgres.Port
+	}
+	if c.CDC.DB == "" {
+		c.CDC.DB = c.Postgres.DBname
+	}
+
+	var errs []error
+	required := func(key, v string) {
+		if strings.TrimSpace(v) == "" {
+			errs = append(errs, fmt.Errorf("%s is required", key))
+		}
+	}
+	positive := func(key string, v int) {
+		if v <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be > 0, got %d", key, v))
+		}
+	}
+	positiveDuration := func(key string, v time.Duration) {
+		if v <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be > 0, got %s", key, v))
+		}
+	}
+
+	required("postgres.host", c.Postgres.Host)
+	required("postgres.user", c.Postgres.User)
+	required("postgres.dbname", c.Postgres.DBname)
+	if port, err := strconv.Atoi(c.Postgres.Port); err != nil || port <= 0 || port > 65535 {
+		errs = append(errs, fmt.Errorf("postgres.port must be a valid TCP port, got %q", c.Postgres.Port))
+	}
+	positive("postgres.max_conns", c.Postgres.MaxConns)
+	if c.Postgres.MinConns < 0 || c.Postgres.MinConns > c.Postgres.MaxConns {
+		errs = append(errs, fmt.Errorf("postgres.min_conns must be in [0, postgres.max_conns], got %d", c.Postgres.MinConns))
+	}
+	positiveDuration("postgres.max_conn_lifetime", c.Postgres.MaxConnLifetime)
+	positiveDuration("postgres.max_conn_idle_time", c.Postgres.MaxConnIdleTime)
+	positiveDuration("postgres.connect_timeout", c.Postgres.ConnectTimeout)
+	positive("postgres.connect_attempts", c.Postgres.ConnectAttempts)
+
+	required("redis.addr", c.Redis.Addr)
+	if c.Redis.DB < 0 || c.Redis.DB > 15 {
+		errs = append(errs, fmt.Errorf("redis.db must be in [0, 15], got %d", c.Redis.DB))
+	}
+
+	required("cdc.replicator", c.CDC.Replicator)
+	required("cdc.slot", c.CDC.Slot)
+	required("cdc.output_plugin", c.CDC.OutputPlugin)
+	required("cdc.publication", c.CDC.Publication)
+
+	required("kafka.bootstrap_servers", c.Kafka.BootstrapServers)
+
+	required("crawler.seed_file", c.Crawler.SeedFile)
+	required("crawler.wiki", c.Crawler.Wiki)
+	positive("crawler.raw_data_queue_cap", c.Crawler.RawDataQueueCap)
+	positiveDuration("crawler.timeout", c.Crawler.Timeout)
+	positiveDuration("crawler.idle_conn_timeout", c.Crawler.IdleConnTimeout)
+	positive("crawler.max_idle_conns", c.Crawler.MaxIdleConns)
+	positive("crawler.max_idle_conns_per_host", c.Crawler.MaxIdleConnsPerHost)
+	if c.Crawler.BatchSize < 1 || c.Crawler.BatchSize > 50 {
+		errs = append(errs, fmt.Errorf("crawler.batch_size must be in [1, 50], got %d", c.Crawler.BatchSize))
+	}
+	if c.Crawler.MaxDepth < 0 {
+		errs = append(errs, fmt.Errorf("crawler.max_depth must be >= 0, got %d", c.Crawler.MaxDepth))
+	}
+	positive("crawler.fetch_workers", c.Crawler.FetchWorkers)
+	if c.Crawler.RateLimit < 0 {
+		errs = append(errs, fmt.Errorf("crawler.rate_limit must be >= 0, got %d", c.Crawler.RateLimit))
+	}
+	positive("crawler.rate_burst", c.Crawler.RateBurst)
+	if c.Crawler.StatsInterval < 0 {
+		errs = append(errs, fmt.Errorf("crawler.stats_interval must be >= 0, got %s", c.Crawler.StatsInterval))
+	}
+	positive("crawler.max_attempts", c.Crawler.MaxAttempts)
+	positiveDuration("crawler.retry_base", c.Crawler.RetryBase)
+	positiveDuration("crawler.retry_max", c.Crawler.RetryMax)
+	if c.Crawler.Maxlag < 0 {
+		errs = append(errs, fmt.Errorf("crawler.maxlag must be >= 0, got %d", c.Crawler.Maxlag))
+	}
+	if c.Crawler.MinConcurrency < 0 || c.Crawler.MinConcurrency > c.Crawler.FetchWorkers {
+		errs = append(errs, fmt.Errorf("crawler.min_concurrency must be in [0, crawler.fetch_workers], got %d",
+			c.Crawler.MinConcurrency))
+	}
+	if c.Crawler.LatencyTarget < 0 {
+		errs = append(errs, fmt.Errorf("crawler.latency_target must be >= 0, got %s", c.Crawler.LatencyTarget))
+	}
+	if c.Crawler.BreakerThreshold < 0 {
+		errs = append(errs, fmt.Errorf("crawler.breaker_threshold must be >= 0, got %d", c.Crawler.BreakerThreshold))
+	}
+	positiveDuration("crawler.breaker_cooldown", c.Crawler.BreakerCooldown)
+	switch c.Crawler.ArchiveMode {
+	case "", "record", "replay":
+	default:
+		errs = append(errs, fmt.Errorf("crawler.archive_mode must be empty, record or replay, got %q", c.Crawler.ArchiveMode))
+	}
+	if c.Crawler.ArchiveMode != "" {
+		required("crawler.archive_file", c.Crawler.ArchiveFile)
+	}
+
+	if c.Frontier.Backend != "postgres" && c.Frontier.Backend != "redis" {
+		errs = append(errs, fmt.Errorf("frontier.backend must be postgres or redis, got %q", c.Frontier.Backend))
+	}
+	positiveDuration("frontier.lease_timeout", c.Frontier.LeaseTimeout)
+	positiveDuration("frontier.poll_interval", c.Frontier.PollInterval)
+
+	positive("handler.workers", c.Handler.Workers)
+	positive("handler.task_queue_cap", c.Handler.TaskQueueCap)
+	if c.Handler.PushTimeout < 0 {
+		errs = append(errs, fmt.Errorf("handler.push_timeout must be >= 0, got %s", c.Handler.PushTimeout))
+	}
+
+	if c.Limiter.ReloadInterval < 0 {
+		errs = append(errs, fmt.Errorf("rate_limiter.reload_interval must be >= 0, got %s", c.Limiter.ReloadInterval))
+	}
+	required("rate_limiter.key_prefix", c.Limiter.KeyPrefix)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("[Config] invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// PostGresConfig returns the settings for dbclient.NewPostgresClient.
+func (c *Config) PostGresConfig() dbclient.PostGresConfig {
+	return dbclient.PostGresConfig{
+		Host:     c.Postgres.Host,
+		Port:     c.Postgres.Port,
+		User:     c.Postgres.User,
+		Password: c.Postgres.Password,
+		DBname:   c.Postgres.DBname,
+
+		MaxConns:        c.Postgres.MaxConns,
+		MinConns:        c.Postgres.MinConns,
+		MaxConnLifetime: c.Postgres.MaxConnLifetime,
+		MaxConnIdleTime: c.Postgres.MaxConnIdleTime,
+		ConnectTimeout:  c.Postgres.ConnectTimeout,
+		ConnectAttempts: c.Postgres.ConnectAttempts,
+	}
+}
+
+// RedisConfig returns the settings for redisclient.InitSingleton.
+func (c *Config) RedisConfig() redisclient.RedisConfig {
+	return redisclient.RedisConfig{
+		Addr:     c.Redis.Addr,
+		Password: c.Redis.Password,
+		DB:       c.Redis.DB,
+	}
+}
+
+// CDCConfig returns the settings for cdc.NewCDCClient.
+func (c *Config) CDCConfig() *cdc.CDCConfig {
+	return &cdc.CDCConfig{
+		Replicator:       c.CDC.Replicator,
+		Psw:              c.CDC.Password,
+		Address:          c.CDC.Address,
+		DB:               c.CDC.DB,
+		Replication_slot: c.CDC.Slot,
+		OutputPlugin:     c.CDC.OutputPlugin,
+		Publication:      c.CDC.Publication,
+	}
+}
+
+// KafkaConfig returns the producer settings for the given topic.
+func (c *Config) KafkaConfig(topic string) kafkaclient.KafkaConfig {
+	return kafkaclient.KafkaConfig{
+		BootstrapServers: c.Kafka.BootstrapServers,
+		Topic:            topic,
+		ExtraConfig:      c.Kafka.ExtraConfig,
+	}
+}
+
+// ///////////////////////////////////////////////////////////////////////////////////////
+type option struct {
+	key   string // YAML path, also used as the flag name
+	ptr   any    // *string, *int or *time.Duration inside Config
+	usage string
+}
+
+func (o option) env() string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(o.key, ".", "_"))
+}
+
+func (o option) set(raw string) error {
+	switch p := o.ptr.(type) {
+	case *string:
+		*p = raw
+	case *int:
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		*p = v
+	case *time.Duration:
+		v, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		*p = v
+	default:
+		return fmt.Errorf("unsupported option type %T", o.ptr)
+	}
+	return nil
+}
+
+// options lists every scalar setting that can be overridden from env or flags.
+func (c *Config) options() []option {
+	return []option{
+		{"postgres.host", &c.Postgres.Host, "PostgreSQL host"},
+		{"postgres.port", &c.Postgres.Port, "PostgreSQL port"},
+		{"postgres.user", &c.Postgres.User, "PostgreSQL user"},
+		{"postgres.password", &c.Postgres.Password, "PostgreSQL password"},
+		{"postgres.dbname", &c.Postgres.DBname, "PostgreSQL database"},
+		{"postgres.max_conns", &c.Postgres.MaxConns, "PostgreSQL pool size"},
+		{"postgres.min_conns", &c.Postgres.MinConns, "PostgreSQL connections kept open while idle"},
+		{"postgres.max_conn_lifetime", &c.Postgres.MaxConnLifetime, "recycle PostgreSQL connections older than this"},
+		{"postgres.max_conn_idle_time", &c.Postgres.MaxConnIdleTime, "close PostgreSQL connections idle for longer"},
+		{"postgres.connect_timeout", &c.Postgres.ConnectTimeout, "timeout of one PostgreSQL connection attempt"},
+		{"postgres.connect_attempts", &c.Postgres.ConnectAttempts, "PostgreSQL connection attempts at startup"},
+
+		{"redis.addr", &c.Redis.Addr, "Redis host:port"},
+		{"redis.password", &c.Redis.Password, "Redis password"},
+		{"redis.db", &c.Redis.DB, "Redis logical database index"},
+
+		{"cdc.replicator", &c.CDC.Replicator, "replication user"},
+		{"cdc.password", &c.CDC.Password, "replication user password"},
+		{"cdc.address", &c.CDC.Address, "replication host:port (default postgres host:port)"},
+		{"cdc.db", &c.CDC.DB, "replicated database (default postgres dbname)"},
+		{"cdc.slot", &c.CDC.Slot, "logical replication slot"},
+		{"cdc.output_plugin", &c.CDC.OutputPlugin, "logical decoding output plugin"},
+		{"cdc.publication", &c.CDC.Publication, "publication name"},
+
+		{"kafka.bootstrap_servers", &c.Kafka.BootstrapServers, "Kafka bootstrap servers"},
+
+		{"crawler.seed_file", &c.Crawler.SeedFile, "file with one seed title per line, optionally prefixed by its wiki (en:Title)"},
+		{"crawler.wiki", &c.Crawler.Wiki, "default wiki: language code (vi), host or api.php URL"},
+		{"crawler.raw_data_queue_cap", &c.Crawler.RawDataQueueCap, "capacity of the raw data queue"},
+		{"crawler.timeout", &c.Crawler.Timeout, "HTTP request timeout"},
+		{"crawler.idle_conn_timeout", &c.Crawler.IdleConnTimeout, "how long idle HTTP connections are kept"},
+		{"crawler.max_idle_conns", &c.Crawler.MaxIdleConns, "total idle HTTP connections"},
+		{"crawler.max_idle_conns_per_host", &c.Crawler.MaxIdleConnsPerHost, "idle HTTP connections per host"},
+		{"crawler.max_depth", &c.Crawler.MaxDepth, "deepest hop from a seed whose links are crawled, 0 = unlimited"},
+		{"crawler.batch_size", &c.Crawler.BatchSize, "titles per MediaWiki links query (max 50)"},
+		{"crawler.fetch_workers", &c.Crawler.FetchWorkers, "concurrent fetchers sharing the frontier"},
+		{"crawler.rate_limit", &c.Crawler.RateLimit, "MediaWiki requests per second over all fetchers, 0 = unlimited"},
+		{"crawler.rate_burst", &c.Crawler.RateBurst, "requests allowed at once after an idle period"},
+		{"crawler.stats_interval", &c.Crawler.StatsInterval, "how often per-fetcher stats are logged, 0 = never"},
+		{"crawler.max_attempts", &c.Crawler.MaxAttempts, "tries per MediaWiki request before its titles are marked failed"},
+		{"crawler.retry_base", &c.Crawler.RetryBase, "first retry backoff, doubled on every retry"},
+		{"crawler.retry_max", &c.Crawler.RetryMax, "longest retry backoff, unless Retry-After asks for more"},
+		{"crawler.maxlag", &c.Crawler.Maxlag, "MediaWiki maxlag parameter in seconds, 0 = not sent"},
+		{"crawler.min_concurrency", &c.Crawler.MinConcurrency, "floor of the adaptive in-flight request limit, 0 = fixed at fetch_workers"},
+		{"crawler.latency_target", &c.Crawler.LatencyTarget, "responses slower than this lower the in-flight limit, 0 = only 429/503 do"},
+		{"crawler.breaker_threshold", &c.Crawler.BreakerThreshold, "failed requests in a row that open the circuit breaker, 0 = no breaker"},
+		{"crawler.breaker_cooldown", &c.Crawler.BreakerCooldown, "pause before a probe request while the breaker is open"},
+		{"crawler.archive_mode", &c.Crawler.ArchiveMode, "record every MediaWiki response to crawler.archive_file, or replay from it; empty = live"},
+		{"crawler.archive_file", &c.Crawler.ArchiveFile, "gzip JSON lines archive of MediaWiki responses"},
+
+		{"frontier.backend", &c.Frontier.Backend, "durable crawl frontier: postgres or redis"},
+		{"frontier.lease_timeout", &c.Frontier.LeaseTimeout, "in-flight titles are handed out again after this"},
+		{"frontier.poll_interval", &c.Frontier.PollInterval, "wait when the frontier has nothing pending"},
+
+		{"handler.workers", &c.Handler.Workers, "raw data handler workers"},
+		{"handler.task_queue_cap", &c.Handler.TaskQueueCap, "raw data handler task queue capacity"},
+		{"handler.push_timeout", &c.Handler.PushTimeout, "wait for a free handler worker before spilling the page, 0 = wait forever"},
+		{"handler.spill_file", &c.Handler.SpillFile, "file for pages the handler workers cannot take in time, empty = drop them"},
+
+		{"rate_limiter.reload_interval", &c.Limiter.ReloadInterval, "how often rate_limiter_rules is checked for changes, 0 = ignore the table"},
+		{"rate_limiter.key_prefix", &c.Limiter.KeyPrefix, "Redis key prefix of the shared token buckets"},
+
+		{"health.addr", &c.Health.Addr, "listen address of GET /healthz, empty = no health server"},
+	}
+}
+
+// lookupConfigPath finds -config/--config before the flag set is parsed, so the
+// file can be loaded underneath env and flag overrides. Like fs.Parse it stops at
+// the first positional argument; fs tells which flags take no value (bool flags).
+func lookupConfigPath(fs *flag.FlagSet, args []string) string {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" || a == "-" {
+			break
+		}
+		name := strings.TrimLeft(a, "-")
+		if name == a {
+			break // first positional argument, the rest is not for us
+		}
+		if v, ok := strings.CutPrefix(name, "config="); ok {
+			return v
+		}
+		if name == "config" {
+			if i+1 < len(args) {
+				return args[i+1]
+			}
+			return ""
+		}
+		if !strings.Contains(name, "=") && !isBoolFlag(fs, name) {
+			i++ // skip the value of "-name value"
+		}
+	}
+	return ""
+}
+
+func isBoolFlag(fs *flag.FlagSet, name string) bool {
+	f := fs.Lookup(name)
+	if f == nil {
+		return false
+	}
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
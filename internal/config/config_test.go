@@ -0,0 +1,190 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTOMLMatchesYAML(t *testing.T) {
+	fromYAML, fromTOML := Default(), Default()
+	if err := fromYAML.LoadFile("../../deployments/wikicrawler.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fromTOML.LoadFile("../../deployments/wikicrawler.toml"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromYAML, fromTOML) {
+		t.Errorf("TOML and YAML examples differ:\nyaml %+v\ntoml %+v", fromYAML, fromTOML)
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "c.toml")
+	data := `# staging
+[postgres]
+host = "db.staging" # inline comment
+port = "6543"
+
+[crawler]
+wiki = 'en'
+timeout = "45s"
+batch_size = 2_0
+
+[kafka]
+extra."linger.ms" = "5"
+bootstrap_servers = """
+kafka-1:9092"""
+
+[kafka.extra]
+"acks" = "all"
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := Default()
+	if err := c.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if c.Postgres.Host != "db.staging" || c.Postgres.Port != "6543" || c.Postgres.User != "erduser" {
+		t.Errorf("postgres = %+v", c.Postgres)
+	}
+	if c.Crawler.Wiki != "en" || c.Crawler.Timeout != 45*time.Second || c.Crawler.BatchSize != 20 {
+		t.Errorf("crawler = %+v", c.Crawler)
+	}
+	if c.Kafka.ExtraConfig["acks"] != "all" || c.Kafka.ExtraConfig["linger.ms"] != "5" {
+		t.Errorf("kafka.extra = %v", c.Kafka.ExtraConfig)
+	}
+	if c.Kafka.BootstrapServers != "kafka-1:9092" {
+		t.Errorf("kafka.bootstrap_servers = %q", c.Kafka.BootstrapServers)
+	}
+}
+
+func TestLoadTOMLErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"unknown key":   "[postgres]\nhots = \"x\"\n",
+		"bare string":   "[postgres]\nhost = localhost\n",
+		"set twice":     "[postgres]\nhost = \"a\"\nhost = \"b\"\n",
+		"unterminated":  "[postgres]\nhost = \"a\n",
+		"table of list": "[[postgres]]\n",
+		"wrong type":    "[crawler]\nbatch_size = \"many\"\n",
+	} {
+		path := filepath.Join(t.TempDir(), "c.toml")
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := Default().LoadFile(path); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestLookupConfigPath(t *testing.T) {
+	fs := flag.NewFlagSet("path", flag.ContinueOnError)
+	fs.Bool("verbose", false, "")
+	fs.Int("max-hops", 6, "")
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-config", "a.yaml", "x"}, "a.yaml"},
+		{[]string{"--config=b.toml"}, "b.toml"},
+		{[]string{"-max-hops", "4", "-config", "c.yaml"}, "c.yaml"},
+		{[]string{"-verbose", "-config=d.yaml"}, "d.yaml"},
+		// a positional argument ends the flags, even when it looks like one later
+		{[]string{"-max-hops", "4", "src", "-config", "dst"}, ""},
+		{[]string{"-verbose", "src", "-config=e.yaml"}, ""},
+		{[]string{"--", "-config", "f.yaml"}, ""},
+	} {
+		if got := lookupConfigPath(fs, tc.args); got != tc.want {
+			t.Errorf("lookupConfigPath(%q) = %q, want %q", tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "c.yaml")
+	data := "postgres:\n  host: from-file\n  port: \"6000\"\n  user: file-user\ncrawler:\n  batch_size: 10\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(ConfigEnv, path)
+	t.Setenv("WIKICRAWLER_POSTGRES_HOST", "from-env")
+	t.Setenv("WIKICRAWLER_POSTGRES_PORT", "7000")
+	t.Setenv("WIKICRAWLER_CRAWLER_TIMEOUT", "12s")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	c, err := Load(fs, []string{"-postgres.port", "8000", "-crawler.batch_size=20", "Page"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		key       string
+		got, want any
+	}{
+		{"postgres.user (file)", c.Postgres.User, "file-user"},
+		{"postgres.host (env over file)", c.Postgres.Host, "from-env"},
+		{"postgres.port (flag over env)", c.Postgres.Port, "8000"},
+		{"crawler.batch_size (flag over file)", c.Crawler.BatchSize, 20},
+		{"crawler.timeout (env over default)", c.Crawler.Timeout, 12 * time.Second},
+		{"postgres.dbname (default)", c.Postgres.DBname, "wikidb"},
+		{"cdc.address (derived)", c.CDC.Address, "from-env:8000"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s = %v, want %v", tc.key, tc.got, tc.want)
+		}
+	}
+	if args := fs.Args(); len(args) != 1 || args[0] != "Page" {
+		t.Errorf("args = %q", args)
+	}
+}
+
+func TestLoadRejectsBadEnv(t *testing.T) {
+	t.Setenv("WIKICRAWLER_CRAWLER_BATCH_SIZE", "lots")
+	if _, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), nil); err == nil ||
+		!strings.Contains(err.Error(), "WIKICRAWLER_CRAWLER_BATCH_SIZE") {
+		t.Errorf("err = %v, want it to name WIKICRAWLER_CRAWLER_BATCH_SIZE", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("defaults: %v", err)
+	}
+	for _, tc := range []struct {
+		key    string // named in the error
+		change func(c *Config)
+	}{
+		{"postgres.host", func(c *Config) { c.Postgres.Host = " " }},
+		{"postgres.port", func(c *Config) { c.Postgres.Port = "70000" }},
+		{"postgres.min_conns", func(c *Config) { c.Postgres.MinConns = c.Postgres.MaxConns + 1 }},
+		{"redis.db", func(c *Config) { c.Redis.DB = 16 }},
+		{"crawler.batch_size", func(c *Config) { c.Crawler.BatchSize = 51 }},
+		{"crawler.max_depth", func(c *Config) { c.Crawler.MaxDepth = -1 }},
+		{"crawler.min_concurrency", func(c *Config) { c.Crawler.MinConcurrency = c.Crawler.FetchWorkers + 1 }},
+		{"crawler.retry_base", func(c *Config) { c.Crawler.RetryBase = 0 }},
+		{"crawler.archive_mode", func(c *Config) { c.Crawler.ArchiveMode = "rewind" }},
+		{"crawler.archive_file", func(c *Config) { c.Crawler.ArchiveMode, c.Crawler.ArchiveFile = "replay", "" }},
+		{"frontier.backend", func(c *Config) { c.Frontier.Backend = "kafka" }},
+		{"handler.push_timeout", func(c *Config) { c.Handler.PushTimeout = -time.Second }},
+		{"rate_limiter.key_prefix", func(c *Config) { c.Limiter.KeyPrefix = "" }},
+	} {
+		c := Default()
+		tc.change(c)
+		err := c.Validate()
+		if err == nil || !strings.Contains(err.Error(), tc.key) {
+			t.Errorf("%s: err = %v", tc.key, err)
+		}
+	}
+
+	// Every problem is reported at once
+	c := Default()
+	c.Redis.DB, c.Crawler.BatchSize = -1, 0
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "redis.db") || !strings.Contains(err.Error(), "crawler.batch_size") {
+		t.Errorf("two errors: %v", err)
+	}
+}
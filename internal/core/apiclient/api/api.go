@@ -1,33 +1,58 @@
 package api
 
 import (
-	"fmt"
 	"net/url"
+	"strconv"
 	"strings"
 )
 
+// MaxTitlesPerQuery is how many titles MediaWiki accepts in one titles= parameter.
+const MaxTitlesPerQuery = 50
+
 type WiKiAPI struct {
-	baseURL string
+	endpoint string
+	maxlag   int
 }
 
-func NewWikiAPI() *WiKiAPI {
+// NewWikiAPI builds the links query for one wiki, given as a language code
+// ("vi"), a host ("vi.wikipedia.org") or a full api.php endpoint URL.
+// With maxlag > 0 the server answers a "maxlag" error while its replicas lag
+// more than maxlag seconds, see https://www.mediawiki.org/wiki/Manual:Maxlag_parameter.
+func NewWikiAPI(wiki string, maxlag int) *WiKiAPI {
 	return &WiKiAPI{
-		baseURL: "https://en.wikipedia.org/w/api.php?action=query&prop=links&format=json&plnamespace=0&pllimit=max&titles=%s",
+		endpoint: Endpoint(wiki),
+		maxlag:   maxlag,
 	}
 }
 
-// URLwithTitle returns a properly encoded URL for a given title
-func (w *WiKiAPI) URLwithTitle(title string) string {
-	// Replace spaces with '+' instead of using QueryEscape
-	safeTitle := strings.ReplaceAll(title, " ", "+")
-	requestURL := fmt.Sprintf(w.baseURL, safeTitle)
-	return requestURL
+// Endpoint returns the api.php URL of a wiki.
+func Endpoint(wiki string) string {
+	switch {
+	case strings.Contains(wiki, "://"):
+		return wiki
+	case strings.Contains(wiki, "."):
+		return "https://" + wiki + "/w/api.php"
+	default:
+		return "https://" + wiki + ".wikipedia.org/w/api.php"
+	}
 }
 
-// URLwithTitleAndPLcontinue returns a properly encoded URL with pagination
-func (w *WiKiAPI) URLwithTitleAndPLcontinue(title string, plcontinue string) string {
-	safeTitle := strings.ReplaceAll(title, " ", "+")
-	requestURL := fmt.Sprintf(w.baseURL, safeTitle)
-	requestURL += "&plcontinue=" + url.QueryEscape(plcontinue) // plcontinue may contain special characters
-	return requestURL
+// URLwithTitles returns the links query for up to MaxTitlesPerQuery titles,
+// continuing from plcontinue when it is not empty.
+func (w *WiKiAPI) URLwithTitles(titles []string, plcontinue string) string {
+	params := url.Values{}
+	params.Set("action", "query")
+	params.Set("prop", "links")
+	params.Set("format", "json")
+	params.Set("plnamespace", "0")
+	params.Set("pllimit", "max")
+	params.Set("redirects", "1") // answer with the redirect target's page
+	params.Set("titles", strings.Join(titles, "|"))
+	if w.maxlag > 0 {
+		params.Set("maxlag", strconv.Itoa(w.maxlag))
+	}
+	if plcontinue != "" {
+		params.Set("plcontinue", plcontinue) // plcontinue may contain special characters
+	}
+	return w.endpoint + "?" + params.Encode()
 }
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"wikicrawler/internal/model"
+)
+
+var (
+	ErrMissingTitle = errors.New("page does not exist")
+	ErrInvalidTitle = errors.New("invalid title")
+)
+
+// Error is the "error" object MediaWiki answers a rejected request with,
+// still with HTTP 200 (e.g. code "maxlag" or "toomanyvalues").
+type Error struct {
+	Code string
+	Info string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("mediawiki error %s: %s", e.Code, e.Info)
+}
+
+// IsRetryable reports whether err is a MediaWiki error that goes away by
+// waiting: replication lag (maxlag) or a server side rate limit.
+func IsRetryable(err error) bool {
+	var e *Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	switch e.Code {
+	case "maxlag", "ratelimited", "readonly", "internal_api_error_DBQueryTimeoutError":
+		return true
+	}
+	return false
+}
+
+// TitleError reports a requested title that has no page; Err is
+// ErrMissingTitle or ErrInvalidTitle.
+type TitleError struct {
+	Title  string
+	Reason string // invalidreason, only for invalid titles
+	Err    error
+}
+
+func (e *TitleError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("%s: %v (%s)", e.Title, e.Err, e.Reason)
+	}
+	return fmt.Sprintf("%s: %v", e.Title, e.Err)
+}
+
+func (e *TitleError) Unwrap() error { return e.Err }
+
+// CheckResponse returns the request-level error of a response as an *Error.
+func CheckResponse(res *model.WikiLinksResponse) error {
+	if res.Error == nil {
+		return nil
+	}
+	return &Error{Code: res.Error.Code, Info: res.Error.Info}
+}
+
+// PageError returns a *TitleError when page is missing or invalid, nil otherwise.
+func PageError(page model.WikiPage) error {
+	switch {
+	case bool(page.Invalid):
+		return &TitleError{Title: page.Title, Reason: page.InvalidReason, Err: ErrInvalidTitle}
+	case bool(page.Missing):
+		return &TitleError{Title: page.Title, Err: ErrMissingTitle}
+	}
+	return nil
+}
+
+// Warnings flattens the warnings of a response to "module: text" lines.
+func Warnings(res *model.WikiLinksResponse) []string {
+	var out []string
+	for module, w := range res.Warnings {
+		out = append(out, module+": "+w.Text)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -2,124 +2,249 @@ package apiclient
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"wikicrawler/internal/core/apiclient/api"
 	"wikicrawler/internal/core/apiclient/rawdatafetcher"
 	"wikicrawler/internal/infra"
 	"wikicrawler/internal/model"
-	"wikicrawler/internal/utils/processor"
+	"wikicrawler/internal/utils/ratelimit"
+	"wikicrawler/internal/utils/retry"
 )
 
+// APIClient runs a pool of fetch workers that lease titles from the frontier
+// and queue their links for the RawDataHandler. All workers share one HTTP
+// transport and one rate limit.
 type APIClient struct {
-	processor.BaseProcessor
-	store   *infra.WikiStore
-	api     *api.WiKiAPI
-	fetcher *rawdatafetcher.RawDataFetcher
+	store         *infra.WikiStore
+	apis          map[string]*api.WiKiAPI // one per wiki, created on first use
+	apisMu        sync.Mutex
+	defaultWiki   string // for frontier items queued before titles carried a wiki
+	fetcher       *rawdatafetcher.RawDataFetcher
+	limiter       *ratelimit.TokenBucket // every MediaWiki request, over all workers
+	retryPolicy   retry.Policy
+	maxlag        int           // seconds of replication lag after which MediaWiki asks us to back off, 0 = not sent
+	pollInterval  time.Duration // wait when the frontier has nothing to lease
+	batchSize     int           // titles per titles= query, at most api.MaxTitlesPerQuery
+	workers       []*fetchWorker
+	statsInterval time.Duration
+	stopStats     chan struct{}
 }
 
-func NewAPIClient(store *infra.WikiStore, Timeout, IdleConnTimeout time.Duration, MaxIdleConns, MaxIdleConnsPerHost int) *APIClient {
+func NewAPIClient(store *infra.WikiStore, Timeout, IdleConnTimeout time.Duration, MaxIdleConns, MaxIdleConnsPerHost int,
+	pollInterval time.Duration, defaultWiki string, batchSize, nworkers int, limiter *ratelimit.TokenBucket,
+	retryPolicy retry.Policy, maxlag int, statsInterval time.Duration) *APIClient {
 	s := &APIClient{
-		store:   store,
-		api:     api.NewWikiAPI(),
-		fetcher: rawdatafetcher.NewRawDataFetcher(Timeout, IdleConnTimeout, MaxIdleConns, MaxIdleConnsPerHost),
+		store:         store,
+		apis:          make(map[string]*api.WiKiAPI),
+		defaultWiki:   defaultWiki,
+		fetcher:       rawdatafetcher.NewRawDataFetcher(Timeout, IdleConnTimeout, MaxIdleConns, MaxIdleConnsPerHost),
+		limiter:       limiter,
+		retryPolicy:   retryPolicy,
+		maxlag:        maxlag,
+		pollInterval:  pollInterval,
+		batchSize:     min(batchSize, api.MaxTitlesPerQuery),
+		statsInterval: statsInterval,
+	}
+	for i := 0; i < max(nworkers, 1); i++ {
+		s.workers = append(s.workers, newFetchWorker(i, s))
 	}
-	s.Init(s)
 	return s
 }
 
-func (a *APIClient) RunningTask() {
+func (a *APIClient) Start() error {
+	for _, w := range a.workers {
+		if err := w.Start(); err != nil {
+			return err
+		}
+	}
+	if a.statsInterval > 0 {
+		a.stopStats = make(chan struct{})
+		go a.logStats(a.stopStats)
+	}
+	return nil
+}
+
+// Stop stops the workers, waits for the batches they are fetching and then
+// closes the fetcher, so a recorded crawl has every response it fetched.
+func (a *APIClient) Stop() error {
+	for _, w := range a.workers {
+		if err := w.Stop(); err != nil {
+			return err
+		}
+	}
+	for _, w := range a.workers {
+		w.Wait()
+	}
+	if a.stopStats != nil {
+		close(a.stopStats)
+		a.stopStats = nil
+	}
+	for _, s := range a.Stats() {
+		fmt.Printf("[APIClient] %s\n", s)
+	}
+	return a.fetcher.Close()
+}
 
-	title := <-a.store.TitlsToQueryQ
-	var plcontinue string
+// Stats returns the stats of every fetch worker.
+func (a *APIClient) Stats() []WorkerStats {
+	stats := make([]WorkerStats, len(a.workers))
+	for i, w := range a.workers {
+		stats[i] = w.stats()
+	}
+	return stats
+}
 
+func (a *APIClient) logStats(stop <-chan struct{}) {
+	ticker := time.NewTicker(a.statsInterval)
+	defer ticker.Stop()
 	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			fmt.Printf("[APIClient] concurrency limit %d of %d fetchers\n", a.ConcurrencyLimit(), len(a.workers))
+			for _, s := range a.Stats() {
+				fmt.Printf("[APIClient] %s\n", s)
+			}
+		}
+	}
+}
 
-		var requestURL string
+// Record saves every MediaWiki response to an archive, see RawDataFetcher.Record.
+func (a *APIClient) Record(path string) error {
+	return a.fetcher.Record(path)
+}
 
-		if plcontinue != "" {
-			requestURL = a.api.URLwithTitleAndPLcontinue(url.QueryEscape(title.Title), url.QueryEscape(plcontinue))
-		} else {
-			requestURL = a.api.URLwithTitle(url.QueryEscape(title.Title))
-		}
+// Replay serves the crawl from an archive instead of MediaWiki.
+func (a *APIClient) Replay(path string) error {
+	return a.fetcher.Replay(path)
+}
 
-		res, err := a.makeRequestWithRetry(requestURL)
-		if err != nil {
-			fmt.Printf("[APIClient] Request error for %s: %v\n", title, err)
-			return
-		}
-		defer res.Body.Close()
+// SetLimiter puts l in front of every MediaWiki request, next to the global rate limit.
+func (a *APIClient) SetLimiter(l rawdatafetcher.Limiter) {
+	a.fetcher.SetLimiter(l)
+}
 
-		if !strings.Contains(res.Header.Get("Content-Type"), "application/json") {
-			fmt.Printf("[APIClient] Unexpected content type for %s: %s\n", title, res.Header.Get("Content-Type"))
-			return
-		}
+// SetConcurrency lets c adapt the number of requests in flight over all workers.
+func (a *APIClient) SetConcurrency(c *rawdatafetcher.AIMD) {
+	a.fetcher.SetConcurrency(c)
+}
 
-		var result model.WikiLinksResponse
-		if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
-			fmt.Printf("[APIClient] Failed to decode JSON for %s: %v\n", title, err)
-			return
-		}
-		//fmt.Println(result)
+// SetBreaker pauses the workers while b is open.
+func (a *APIClient) SetBreaker(b *rawdatafetcher.Breaker) {
+	a.fetcher.SetBreaker(b)
+}
 
-		a.store.RawDataQ <- model.RawDataWiki{
-			TitleQ:   title,
-			LinksRes: result,
-		}
+// Breaker returns the circuit breaker in front of MediaWiki, or nil.
+func (a *APIClient) Breaker() *rawdatafetcher.Breaker {
+	return a.fetcher.Breaker()
+}
 
-		if result.Continue.Plcontinue == "" {
-			break
-		}
-		plcontinue = result.Continue.Plcontinue
+// ConcurrencyLimit returns how many requests may currently be in flight.
+func (a *APIClient) ConcurrencyLimit() int {
+	if c := a.fetcher.Concurrency(); c != nil {
+		return c.Limit()
 	}
+	return len(a.workers)
 }
 
-func (a *APIClient) makeRequestWithRetry(url string) (*http.Response, error) {
-	maxRetries := 3
-	baseDelay := 1 * time.Second
-	fmt.Printf("[APIClient] url = %s\n", url)
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		res, err := a.fetcher.GetRawData(url)
+// apiFor returns the WiKiAPI of the wiki a title belongs to.
+func (a *APIClient) apiFor(wiki string) *api.WiKiAPI {
+	a.apisMu.Lock()
+	defer a.apisMu.Unlock()
+	w, ok := a.apis[wiki]
+	if !ok {
+		w = api.NewWikiAPI(wiki, a.maxlag)
+		a.apis[wiki] = w
+	}
+	return w
+}
 
+// fetchPage fetches and decodes one MediaWiki response, retrying under the
+// retry policy. The error is a *retry.ExhaustedError once the policy gave up,
+// or the permanent error that stopped it.
+func (a *APIClient) fetchPage(requestURL string) (*model.WikiLinksResponse, error) {
+	fmt.Printf("[APIClient] url = %s\n", requestURL)
+	var result *model.WikiLinksResponse
+	err := retry.Do(a.retryPolicy, func(attempt int) error {
+		res, err := a.fetchOnce(requestURL)
 		if err != nil {
-			if attempt == maxRetries-1 {
-				return nil, fmt.Errorf("[APIClient] request failed after %d attempts: %w", maxRetries, err)
+			if !retry.IsPermanent(err) {
+				fmt.Printf("[APIClient] attempt %d failed: %v\n", attempt, err)
 			}
-			// Wait before retrying (exponential backoff)
-			delay := baseDelay * time.Duration(1<<attempt) // 1s, 2s, 4s
-			time.Sleep(delay)
-			continue
+			return err
 		}
+		result = res
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
 
-		// Check for specific HTTP errors that warrant retry
-		if res.StatusCode == http.StatusTooManyRequests || // 429 Rate Limited
-			res.StatusCode >= 500 { // 5xx Server Errors
-			res.Body.Close() // Close before retry
-
-			if attempt == maxRetries-1 {
-				return nil, fmt.Errorf("[APIClient] request failed with status %d after %d attempts", res.StatusCode, maxRetries)
-			}
+// fetchOnce makes a single request and classifies its failure: rate limits,
+// server errors and maxlag are retryable (with the server's Retry-After as a
+// hint), anything else the server rejects is permanent.
+func (a *APIClient) fetchOnce(requestURL string) (*model.WikiLinksResponse, error) {
+	a.limiter.Wait()
+	res, err := a.fetcher.GetRawData(requestURL)
+	if errors.Is(err, rawdatafetcher.ErrBreakerOpen) || errors.Is(err, rawdatafetcher.ErrLimiter) {
+		return nil, retry.Permanent(err) // no point retrying, the worker hands the titles back
+	}
+	if errors.Is(err, rawdatafetcher.ErrNotRecorded) {
+		return nil, retry.Permanent(err)
+	}
+	if err != nil {
+		return nil, err // network errors and timeouts are worth another try
+	}
+	defer res.Body.Close()
+
+	hint := retryAfter(res.Header.Get("Retry-After"))
+	switch {
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500:
+		return nil, retry.After(fmt.Errorf("[APIClient] status code %d", res.StatusCode), hint)
+	case res.StatusCode != http.StatusOK:
+		return nil, retry.Permanent(fmt.Errorf("[APIClient] unexpected status code: %d", res.StatusCode))
+	}
 
-			// For rate limiting, wait longer
-			delay := baseDelay * time.Duration(1<<attempt)
-			if res.StatusCode == http.StatusTooManyRequests {
-				delay *= 2 // Double delay for rate limits
-			}
-			time.Sleep(delay)
-			continue
-		}
+	if !strings.Contains(res.Header.Get("Content-Type"), "application/json") {
+		// Error pages of a proxy in front of the API are HTML
+		return nil, fmt.Errorf("unexpected content type %s", res.Header.Get("Content-Type"))
+	}
 
-		// Check for non-200 status codes that shouldn't be retried
-		if res.StatusCode != http.StatusOK {
-			res.Body.Close()
-			return nil, fmt.Errorf("[APIClient] unexpected status code: %d", res.StatusCode)
+	var result model.WikiLinksResponse
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	for _, w := range api.Warnings(&result) {
+		fmt.Printf("[APIClient] MediaWiki warning %s\n", w)
+	}
+	if err := api.CheckResponse(&result); err != nil {
+		if api.IsRetryable(err) {
+			return nil, retry.After(err, hint)
 		}
-
-		return res, nil
+		return nil, retry.Permanent(err)
 	}
+	return &result, nil
+}
 
-	return nil, fmt.Errorf("[APIClient] unexpected: reached end of retry loop")
+// retryAfter parses a Retry-After header, given in seconds or as an HTTP date.
+func retryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
 }
@@ -0,0 +1,139 @@
+package apiclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+	"wikicrawler/internal/fakewiki"
+	"wikicrawler/internal/infra/frontier"
+)
+
+// fetchAll crawls titles (no children) with one worker and returns the links
+// found for every title.
+func fetchAll(t *testing.T, a *APIClient, f frontier.Frontier, titles []string) map[string][]string {
+	t.Helper()
+	pushTitles(t, f, "", titles...)
+	links := make(map[string][]string)
+	for len(links) < len(titles) {
+		a.workers[0].RunningTask()
+		if counts, _ := f.Counts(); counts[frontier.StateFailed] > 0 {
+			t.Fatalf("frontier %v: a fetch failed", counts)
+		}
+		for len(a.store.RawDataQ) > 0 {
+			data := <-a.store.RawDataQ
+			got := []string{}
+			for _, p := range data.LinksRes.Query.Pages {
+				for _, l := range p.Links {
+					got = append(got, l.Title)
+				}
+			}
+			links[data.TitleQ.Title] = got
+			f.Done(data.TitleQ)
+		}
+	}
+	return links
+}
+
+func TestReplayWithAnotherBatchSize(t *testing.T) {
+	// 30 links per page: a batch of 50 titles needs several plcontinue pages
+	wiki := fakewiki.NewServer(fakewiki.Generate(60, 30, 1), fakewiki.Faults{}, 1)
+	srv := httptest.NewServer(wiki)
+	defer srv.Close()
+
+	titles := []string{"Redirect to Page 10", "page_3", "Nowhere", "Bad[title"}
+	for i := 1; i <= 60; i++ {
+		titles = append(titles, fakewiki.PageTitle(i))
+	}
+	archive := filepath.Join(t.TempDir(), "responses.jsonl.gz")
+
+	f := frontier.NewMemoryFrontier(time.Minute)
+	rec := newTestClient(srv.URL, f, 50)
+	if err := rec.Record(archive); err != nil {
+		t.Fatal(err)
+	}
+	want := fetchAll(t, rec, f, titles)
+	if err := rec.fetcher.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if want["Page 1"] == nil || len(want["Redirect to Page 10"]) != 30 {
+		t.Fatalf("recorded crawl is incomplete: %v", want)
+	}
+
+	// Another batch size, another order and no server
+	srv.Close()
+	reversed := make([]string, len(titles))
+	for i, title := range titles {
+		reversed[len(titles)-1-i] = title
+	}
+	f = frontier.NewMemoryFrontier(time.Minute)
+	rep := newTestClient(srv.URL, f, 7)
+	if err := rep.Replay(archive); err != nil {
+		t.Fatal(err)
+	}
+	if got := fetchAll(t, rep, f, reversed); !reflect.DeepEqual(got, want) {
+		for _, title := range titles {
+			if !reflect.DeepEqual(got[title], want[title]) {
+				t.Errorf("%s: replayed %v, recorded %v", title, got[title], want[title])
+			}
+		}
+	}
+}
+
+// recordedLines counts the responses in an archive.
+func recordedLines(t *testing.T, path string) int {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Count(data, []byte("\n"))
+}
+
+func TestStopRecordsTheResponsesInFlight(t *testing.T) {
+	// Every answer takes 50ms: Stop comes while the first request is in flight
+	wiki := fakewiki.NewServer(fakewiki.Generate(10, 3, 1),
+		fakewiki.Faults{Slow: 1, SlowDelay: 50 * time.Millisecond}, 1)
+	srv := httptest.NewServer(wiki)
+	defer srv.Close()
+	archive := filepath.Join(t.TempDir(), "responses.jsonl.gz")
+
+	f := frontier.NewMemoryFrontier(time.Minute)
+	a := newTestClient(srv.URL, f, 50)
+	if err := a.Record(archive); err != nil {
+		t.Fatal(err)
+	}
+	pushTitles(t, f, "", fakewiki.PageTitle(1))
+	if err := a.Start(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := a.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	served := wiki.Requests()
+	if n := recordedLines(t, archive); served == 0 || n != int(served) {
+		t.Errorf("%d response(s) recorded, %d served", n, served)
+	}
+
+	// Closed: nothing is sent that could not be recorded
+	pushTitles(t, f, "", fakewiki.PageTitle(2))
+	a.workers[0].RunningTask()
+	if wiki.Requests() != served {
+		t.Errorf("%d request(s) sent after Stop", wiki.Requests()-served)
+	}
+}
@@ -0,0 +1,110 @@
+package apiclient
+
+import (
+	"fmt"
+	"strconv"
+	"wikicrawler/internal/core/apiclient/api"
+	"wikicrawler/internal/model"
+)
+
+// fetchBatch queries the links of up to api.MaxTitlesPerQuery titles of one
+// wiki at once, follows plcontinue for the whole batch and queues one
+// RawDataWiki per requested title. It returns how many responses it fetched.
+func (a *APIClient) fetchBatch(wiki string, titles []model.TitleQuery) (int, error) {
+	wikiAPI := a.apiFor(wiki)
+	names := make([]string, len(titles))
+	for i, t := range titles {
+		names[i] = t.Title
+	}
+
+	b := newLinksBatch()
+	var plcontinue string
+	requests := 0
+	for {
+		result, err := a.fetchPage(wikiAPI.URLwithTitles(names, plcontinue))
+		if err != nil {
+			return requests, fmt.Errorf("[APIClient] Request error for %d title(s) starting at %s: %w", len(titles), names[0], err)
+		}
+		requests++
+		b.add(result)
+
+		if result.Continue.Plcontinue == "" {
+			break
+		}
+		plcontinue = result.Continue.Plcontinue
+	}
+
+	for _, t := range titles {
+		data := b.rawData(t)
+		data.Wiki = wiki
+		a.store.RawDataQ <- data
+	}
+	return requests, nil
+}
+
+// linksBatch merges the responses of one batch. With several titles, every
+// plcontinue response lists all pages but only carries the next slice of links.
+type linksBatch struct {
+	pages      map[string]*model.WikiPage // keyed by the page title MediaWiki answers with
+	normalized map[string]string          // requested title -> normalized title
+	redirects  map[string]model.Redirect  // normalized title -> redirect target
+}
+
+func newLinksBatch() *linksBatch {
+	return &linksBatch{
+		pages:      make(map[string]*model.WikiPage),
+		normalized: make(map[string]string),
+		redirects:  make(map[string]model.Redirect),
+	}
+}
+
+func (b *linksBatch) add(res *model.WikiLinksResponse) {
+	for _, n := range res.Query.Normalized {
+		b.normalized[n.From] = n.To
+	}
+	for _, r := range res.Query.Redirects {
+		b.redirects[r.From] = r
+	}
+	for _, p := range res.Query.Pages {
+		page, ok := b.pages[p.Title]
+		if !ok {
+			page = &model.WikiPage{Pageid: p.Pageid, Ns: p.Ns, Title: p.Title,
+				Missing: p.Missing, Invalid: p.Invalid, InvalidReason: p.InvalidReason}
+			b.pages[p.Title] = page
+		}
+		page.Links = append(page.Links, p.Links...)
+	}
+}
+
+// rawData maps a requested title back to its page, through normalization and
+// redirects, keeping only the steps that concern this title.
+func (b *linksBatch) rawData(t model.TitleQuery) model.RawDataWiki {
+	var res model.WikiLinksResponse
+
+	pageTitle := t.Title
+	if to, ok := b.normalized[t.Title]; ok {
+		pageTitle = to
+		res.Query.Normalized = []model.Normalization{{From: t.Title, To: to}}
+	}
+	for seen := map[string]bool{}; !seen[pageTitle]; {
+		seen[pageTitle] = true
+		r, ok := b.redirects[pageTitle]
+		if !ok {
+			break
+		}
+		res.Query.Redirects = append(res.Query.Redirects, r)
+		pageTitle = r.To
+	}
+
+	page, ok := b.pages[pageTitle]
+	if !ok {
+		page = &model.WikiPage{Title: pageTitle}
+	}
+	res.Query.Pages = map[string]model.WikiPage{strconv.Itoa(page.Pageid): *page}
+
+	return model.RawDataWiki{
+		TitleQ:   t,
+		LinksRes: res,
+		Err:      api.PageError(*page),
+	}
+}
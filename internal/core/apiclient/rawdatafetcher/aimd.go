@@ -0,0 +1,90 @@
+package rawdatafetcher
+
+import (
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// AIMD limits the number of requests in flight the way TCP limits its window:
+// every answered request raises the limit by 1/limit (about +1 per round of
+// requests), a 429/503 or a response slower than the latency target halves it.
+// Several throttled answers to requests sent together only count once.
+type AIMD struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	limit    float64
+	min, max float64
+	inFlight int
+
+	latencyTarget time.Duration // 0 = only status codes lower the limit
+	latency       time.Duration // moving average of the answered requests
+	lastDecrease  time.Time
+}
+
+func NewAIMD(minLimit, maxLimit int, latencyTarget time.Duration) *AIMD {
+	minLimit = max(minLimit, 1)
+	maxLimit = max(maxLimit, minLimit)
+	c := &AIMD{
+		limit:         float64(minLimit),
+		min:           float64(minLimit),
+		max:           float64(maxLimit),
+		latencyTarget: latencyTarget,
+	}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Limit returns the number of requests currently allowed in flight.
+func (c *AIMD) Limit() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return int(c.limit)
+}
+
+// InFlight returns the number of requests between Acquire and Release.
+func (c *AIMD) InFlight() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.inFlight
+}
+
+// Acquire blocks until one more request may be sent.
+func (c *AIMD) Acquire() {
+	c.mu.Lock()
+	for c.inFlight >= int(c.limit) {
+		c.cond.Wait()
+	}
+	c.inFlight++
+	c.mu.Unlock()
+}
+
+// Release reports how an acquired request went; status is 0 when it got no answer.
+func (c *AIMD) Release(status int, latency time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight--
+
+	if status != 0 {
+		if c.latency == 0 {
+			c.latency = latency
+		} else {
+			c.latency = (7*c.latency + latency) / 8
+		}
+	}
+
+	throttled := status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+	slow := c.latencyTarget > 0 && latency > c.latencyTarget
+	switch {
+	case throttled || slow:
+		// Requests sent before the last decrease do not count again
+		if time.Since(c.lastDecrease) > max(c.latency, 100*time.Millisecond) {
+			c.limit = math.Max(c.min, c.limit/2)
+			c.lastDecrease = time.Now()
+		}
+	case status > 0 && status < 500:
+		c.limit = math.Min(c.max, c.limit+1/c.limit)
+	}
+	c.cond.Broadcast()
+}
@@ -0,0 +1,360 @@
+package rawdatafetcher
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+	"wikicrawler/internal/model"
+)
+
+// ErrNotRecorded is returned in replay mode for a URL the archive has no response for.
+var ErrNotRecorded = errors.New("[RawDataFetcher] no recorded response")
+
+// ErrArchiveClosed is returned in record mode once the archive is closed: a
+// response that cannot be recorded is not sent at all.
+var ErrArchiveClosed = errors.New("[RawDataFetcher] archive is closed")
+
+// archiveEntry is one line of a gzip compressed JSON lines archive.
+type archiveEntry struct {
+	Time       time.Time   `json:"time"`
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body,omitempty"`     // UTF-8 bodies, i.e. every MediaWiki answer
+	BodyBase64 []byte      `json:"body_b64,omitempty"` // anything else, byte for byte
+}
+
+func (e *archiveEntry) body() []byte {
+	if e.BodyBase64 != nil {
+		return e.BodyBase64
+	}
+	return []byte(e.Body)
+}
+
+func (e *archiveEntry) response(req *http.Request) *http.Response {
+	body := e.body()
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// recorder passes requests to next and appends every response to an archive.
+type recorder struct {
+	next   http.RoundTripper
+	mu     sync.Mutex
+	file   *os.File
+	gz     *gzip.Writer
+	enc    *json.Encoder
+	closed bool
+}
+
+func newRecorder(next http.RoundTripper, path string) (*recorder, error) {
+	// Appending starts a new gzip member; readers see one stream, so a resumed crawl extends its archive
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("[RawDataFetcher] failed to open archive: %w", err)
+	}
+	gz := gzip.NewWriter(f)
+	return &recorder{next: next, file: f, gz: gz, enc: json.NewEncoder(gz)}, nil
+}
+
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	closed := r.closed
+	r.mu.Unlock()
+	if closed {
+		return nil, ErrArchiveClosed
+	}
+	res, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+
+	e := archiveEntry{Time: time.Now(), URL: req.URL.String(), StatusCode: res.StatusCode, Header: res.Header}
+	if utf8.Valid(body) {
+		e.Body = string(body)
+	} else {
+		e.BodyBase64 = body
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Closed while the request was in flight: the caller was meant to wait for it
+	if r.closed {
+		return nil, ErrArchiveClosed
+	}
+	if err := r.enc.Encode(&e); err != nil {
+		return nil, fmt.Errorf("[RawDataFetcher] failed to record %s: %w", e.URL, err)
+	}
+	return res, nil
+}
+
+func (r *recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	if err := r.gz.Close(); err != nil {
+		r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
+
+// replayer answers requests from an archive without touching the network.
+// A URL recorded several times (e.g. a 429 then its retry) is answered in the
+// recorded order; once they are used up the last answer is repeated.
+//
+// A links query whose URL was not recorded as such, because the titles were
+// batched differently (another batch_size, another lease order), is answered
+// from the recorded pages of its titles instead: one response holding every
+// link of every title, with no plcontinue.
+type replayer struct {
+	mu        sync.Mutex
+	responses map[string][]*archiveEntry
+	titles    map[string]*recordedTitles // keyed by titlesKey of the request
+}
+
+func newReplayer(path string) (*replayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("[RawDataFetcher] failed to open archive: %w", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("[RawDataFetcher] archive %s is not gzip: %w", path, err)
+	}
+
+	r := &replayer{responses: map[string][]*archiveEntry{}, titles: map[string]*recordedTitles{}}
+	sc := bufio.NewScanner(gz)
+	sc.Buffer(make([]byte, 0, 1<<20), 64<<20)
+	n := 0
+	for sc.Scan() {
+		var e archiveEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("[RawDataFetcher] archive %s line %d: %w", path, n+1, err)
+		}
+		r.responses[e.URL] = append(r.responses[e.URL], &e)
+		r.index(&e)
+		n++
+	}
+	if err := sc.Err(); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		// A recording cut short by a crash still replays up to its last full line
+		return nil, fmt.Errorf("[RawDataFetcher] failed to read archive %s: %w", path, err)
+	}
+	fmt.Printf("[RawDataFetcher] replaying %d response(s) for %d URL(s) from %s\n", n, len(r.responses), path)
+	return r, nil
+}
+
+// index adds the pages of a successful links query to r.titles.
+func (r *replayer) index(e *archiveEntry) {
+	if e.StatusCode != http.StatusOK {
+		return
+	}
+	u, err := url.Parse(e.URL)
+	if err != nil {
+		return
+	}
+	key, names := titlesKey(u)
+	if key == "" {
+		return
+	}
+	var res model.WikiLinksResponse
+	if err := json.Unmarshal(e.body(), &res); err != nil || res.Error != nil {
+		return // maxlag and other rejected requests carry no pages
+	}
+	t, ok := r.titles[key]
+	if !ok {
+		t = newRecordedTitles()
+		r.titles[key] = t
+	}
+	t.add(names, u.Query().Get("plcontinue") == "", &res)
+}
+
+func (r *replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	url := req.URL.String()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	queue := r.responses[url]
+	if len(queue) == 0 {
+		return r.fromTitles(req)
+	}
+	e := queue[0]
+	if len(queue) > 1 {
+		r.responses[url] = queue[1:]
+	}
+	return e.response(req), nil
+}
+
+// fromTitles builds the answer to a links query out of the recorded pages of
+// its titles, when every one of them was recorded to the end of its links.
+func (r *replayer) fromTitles(req *http.Request) (*http.Response, error) {
+	url := req.URL.String()
+	key, names := titlesKey(req.URL)
+	t := r.titles[key]
+	if key == "" || t == nil || req.URL.Query().Get("plcontinue") != "" {
+		return nil, fmt.Errorf("%w for %s", ErrNotRecorded, url)
+	}
+	res, ok := t.response(names)
+	if !ok {
+		return nil, fmt.Errorf("%w for %s", ErrNotRecorded, url)
+	}
+	body, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	e := archiveEntry{StatusCode: http.StatusOK, Header: http.Header{"Content-Type": {"application/json; charset=utf-8"}}, Body: string(body)}
+	return e.response(req), nil
+}
+
+// titlesKey is the URL of a links query without its titles and plcontinue,
+// i.e. the same for every batch of one wiki, and the titles it asks for.
+func titlesKey(u *url.URL) (string, []string) {
+	params := u.Query()
+	if params.Get("prop") != "links" || params.Get("titles") == "" {
+		return "", nil
+	}
+	names := strings.Split(params.Get("titles"), "|")
+	params.Del("titles")
+	params.Del("plcontinue")
+	k := *u
+	k.RawQuery = params.Encode()
+	return k.String(), names
+}
+
+// recordedTitles merges the recorded links queries of one wiki. Like
+// apiclient.linksBatch, the plcontinue responses of a batch are merged into
+// its pages, which are kept once the last response of the batch is recorded.
+type recordedTitles struct {
+	pages      map[string]*model.WikiPage            // keyed by the page title MediaWiki answered with
+	normalized map[string]string                     // requested title -> normalized title
+	redirects  map[string]model.Redirect             // normalized title -> redirect target
+	complete   map[string]bool                       // requested titles whose batch was recorded to the end
+	batches    map[string]map[string]*model.WikiPage // titles= of a batch -> its pages so far
+}
+
+func newRecordedTitles() *recordedTitles {
+	return &recordedTitles{
+		pages:      make(map[string]*model.WikiPage),
+		normalized: make(map[string]string),
+		redirects:  make(map[string]model.Redirect),
+		complete:   make(map[string]bool),
+		batches:    make(map[string]map[string]*model.WikiPage),
+	}
+}
+
+func (t *recordedTitles) add(names []string, first bool, res *model.WikiLinksResponse) {
+	for _, n := range res.Query.Normalized {
+		t.normalized[n.From] = n.To
+	}
+	for _, r := range res.Query.Redirects {
+		t.redirects[r.From] = r
+	}
+
+	batch := strings.Join(names, "|")
+	pages := t.batches[batch]
+	if first || pages == nil {
+		// A batch fetched again (e.g. after its lease expired) starts over
+		pages = make(map[string]*model.WikiPage)
+		t.batches[batch] = pages
+	}
+	for _, p := range res.Query.Pages {
+		page, ok := pages[p.Title]
+		if !ok {
+			page = &model.WikiPage{Pageid: p.Pageid, Ns: p.Ns, Title: p.Title,
+				Missing: p.Missing, Invalid: p.Invalid, InvalidReason: p.InvalidReason}
+			pages[p.Title] = page
+		}
+		page.Links = append(page.Links, p.Links...)
+	}
+	if res.Continue.Plcontinue != "" {
+		return
+	}
+
+	delete(t.batches, batch)
+	for title, page := range pages {
+		if _, ok := t.pages[title]; !ok {
+			t.pages[title] = page
+		}
+	}
+	for _, name := range names {
+		t.complete[name] = true
+	}
+}
+
+// response answers a query for names, or false when one of them was not
+// recorded to the end.
+func (t *recordedTitles) response(names []string) (*model.WikiLinksResponse, bool) {
+	var res model.WikiLinksResponse
+	res.Query.Pages = make(map[string]model.WikiPage)
+	seen := make(map[string]bool)
+	missing := 0
+	for _, name := range names {
+		if !t.complete[name] {
+			return nil, false
+		}
+		title := name
+		if to, ok := t.normalized[name]; ok {
+			title = to
+			res.Query.Normalized = append(res.Query.Normalized, model.Normalization{From: name, To: to})
+		}
+		for hops := map[string]bool{}; !hops[title]; {
+			hops[title] = true
+			r, ok := t.redirects[title]
+			if !ok {
+				break
+			}
+			if !seen["redirect|"+r.From] {
+				seen["redirect|"+r.From] = true
+				res.Query.Redirects = append(res.Query.Redirects, r)
+			}
+			title = r.To
+		}
+		page, ok := t.pages[title]
+		if !ok {
+			page, ok = t.pages[name] // invalid titles keep the requested spelling
+		}
+		if !ok {
+			return nil, false
+		}
+		if seen[title] {
+			continue
+		}
+		seen[title] = true
+		id := strconv.Itoa(page.Pageid)
+		if page.Pageid == 0 {
+			missing-- // MediaWiki keys pages without an ID -1, -2, ...
+			id = strconv.Itoa(missing)
+		}
+		res.Query.Pages[id] = *page
+	}
+	return &res, true
+}
@@ -0,0 +1,125 @@
+package rawdatafetcher
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrBreakerOpen is returned instead of sending a request while the breaker is open.
+var ErrBreakerOpen = errors.New("[RawDataFetcher] circuit breaker open, upstream looks down")
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"    // requests go through
+	BreakerOpen     BreakerState = "open"      // requests fail fast until the cooldown is over
+	BreakerHalfOpen BreakerState = "half_open" // one probe request decides whether to close again
+)
+
+// Breaker opens after threshold requests in a row got no answer or a 5xx.
+// After cooldown a single probe goes through: its success closes the breaker,
+// its failure opens it for another cooldown.
+type Breaker struct {
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int // consecutive
+	threshold int
+	cooldown  time.Duration
+	openedAt  time.Time
+	probing   bool // the half-open probe is in flight
+}
+
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		state:     BreakerClosed,
+		threshold: max(threshold, 1),
+		cooldown:  cooldown,
+	}
+}
+
+// BreakerStatus is a snapshot of a Breaker for health output.
+type BreakerStatus struct {
+	State    BreakerState `json:"state"`
+	Failures int          `json:"consecutive_failures"`
+	OpenedAt *time.Time   `json:"opened_at,omitempty"`
+	RetryAt  *time.Time   `json:"retry_at,omitempty"` // when the next probe may go
+}
+
+func (b *Breaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := BreakerStatus{State: b.current(), Failures: b.failures}
+	if s.State != BreakerClosed {
+		opened, retry := b.openedAt, b.openedAt.Add(b.cooldown)
+		s.OpenedAt, s.RetryAt = &opened, &retry
+	}
+	return s
+}
+
+// State returns closed, open or half_open.
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.current()
+}
+
+// current moves an open breaker to half-open once its cooldown is over; b.mu is held.
+func (b *Breaker) current() BreakerState {
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.cooldown {
+		b.state = BreakerHalfOpen
+		b.probing = false
+	}
+	return b.state
+}
+
+// Ready reports whether a request would be let through now, without taking
+// the half-open probe. Workers check it before leasing titles.
+func (b *Breaker) Ready() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.current() {
+	case BreakerOpen:
+		return false
+	case BreakerHalfOpen:
+		return !b.probing
+	}
+	return true
+}
+
+// Allow returns ErrBreakerOpen when the request must not be sent. In the
+// half-open state only the first caller gets through, as the probe.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.current() {
+	case BreakerOpen:
+		return ErrBreakerOpen
+	case BreakerHalfOpen:
+		if b.probing {
+			return ErrBreakerOpen
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// Record reports the outcome of an allowed request; status is 0 when it got no answer.
+func (b *Breaker) Record(status int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if status != 0 && status < http.StatusInternalServerError {
+		b.failures = 0
+		b.state = BreakerClosed
+		b.probing = false
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+		b.probing = false
+	}
+}
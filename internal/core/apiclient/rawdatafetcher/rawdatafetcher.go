@@ -1,13 +1,29 @@
 package rawdatafetcher
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"time"
 )
 
+// Limiter is asked before every request and blocks until action may be sent
+// to host, e.g. ratelimiter.RulesLimiter.
+type Limiter interface {
+	Wait(host, action string) error
+}
+
+// ErrLimiter wraps the error of a Limiter that could not be asked (e.g. Redis
+// is down); the request was not sent, so it says nothing about the titles.
+var ErrLimiter = errors.New("[RawDataFetcher] rate limiter unavailable")
+
 type RawDataFetcher struct {
-	httpClient *http.Client
+	httpClient  *http.Client
+	limiter     Limiter
+	concurrency *AIMD     // nil = as many requests in flight as callers
+	breaker     *Breaker  // nil = never stop sending
+	archive     io.Closer // recorder to flush on Close
 }
 
 func NewRawDataFetcher(Timeout, IdleConnTimeout time.Duration, MaxIdleConns, MaxIdleConnsPerHost int) *RawDataFetcher {
@@ -23,16 +39,102 @@ func NewRawDataFetcher(Timeout, IdleConnTimeout time.Duration, MaxIdleConns, Max
 	return r
 }
 
+// Record appends every response to a gzip compressed JSON lines archive at
+// path (URL, status, headers and body). Call Close to flush it.
+func (r *RawDataFetcher) Record(path string) error {
+	rec, err := newRecorder(r.httpClient.Transport, path)
+	if err != nil {
+		return err
+	}
+	r.httpClient.Transport = rec
+	r.archive = rec
+	return nil
+}
+
+// Replay answers every request from an archive written by Record, with no
+// network. Links queries are answered per title, so the archive replays with
+// any batch size; requests it has no response for fail with ErrNotRecorded.
+func (r *RawDataFetcher) Replay(path string) error {
+	rep, err := newReplayer(path)
+	if err != nil {
+		return err
+	}
+	r.httpClient.Transport = rep
+	return nil
+}
+
+// Close flushes the archive of Record. Requests still in flight are not
+// recorded and fail with ErrArchiveClosed, as does every request after it,
+// so callers stop sending before they call Close.
+func (r *RawDataFetcher) Close() error {
+	if r.archive == nil {
+		return nil
+	}
+	return r.archive.Close()
+}
+
+// SetLimiter puts l in front of every request; nil removes it.
+func (r *RawDataFetcher) SetLimiter(l Limiter) {
+	r.limiter = l
+}
+
+// SetConcurrency lets c decide how many requests are in flight at once.
+func (r *RawDataFetcher) SetConcurrency(c *AIMD) {
+	r.concurrency = c
+}
+
+// Concurrency returns the controller set by SetConcurrency, or nil.
+func (r *RawDataFetcher) Concurrency() *AIMD {
+	return r.concurrency
+}
+
+// SetBreaker stops requests while b is open.
+func (r *RawDataFetcher) SetBreaker(b *Breaker) {
+	r.breaker = b
+}
+
+// Breaker returns the breaker set by SetBreaker, or nil.
+func (r *RawDataFetcher) Breaker() *Breaker {
+	return r.breaker
+}
+
 func (r *RawDataFetcher) GetRawData(url string) (*http.Response, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("[RawDataFetcher] failed to create request: %w", err)
 	}
 
+	if r.limiter != nil {
+		// MediaWiki action (query, parse, ...) of the api.php call
+		if err := r.limiter.Wait(req.URL.Hostname(), req.URL.Query().Get("action")); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrLimiter, err)
+		}
+	}
+
 	// Setting User-Agent to be respectful to Wikipedia
 	req.Header.Set("User-Agent", "Entities-Relationship/1.0 (Personal Project)")
 
+	if r.breaker != nil {
+		if err := r.breaker.Allow(); err != nil {
+			return nil, err
+		}
+	}
+	if r.concurrency != nil {
+		r.concurrency.Acquire()
+	}
+
+	start := time.Now()
 	res, err := r.httpClient.Do(req)
+	status := 0
+	if err == nil {
+		status = res.StatusCode
+	}
 
+	if r.concurrency != nil {
+		r.concurrency.Release(status, time.Since(start))
+	}
+	if r.breaker != nil {
+		r.breaker.Record(status)
+	}
 	return res, err
 }
@@ -0,0 +1,135 @@
+package apiclient
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+	"wikicrawler/internal/core/apiclient/rawdatafetcher"
+	"wikicrawler/internal/model"
+	"wikicrawler/internal/utils/processor"
+	"wikicrawler/internal/utils/retry"
+)
+
+// WorkerStats is what one fetch worker has done since it started.
+type WorkerStats struct {
+	ID       int           `json:"id"`
+	Batches  int64         `json:"batches"`  // leases fetched
+	Titles   int64         `json:"titles"`   // titles fetched
+	Requests int64         `json:"requests"` // MediaWiki responses, plcontinue pages included
+	Errors   int64         `json:"errors"`   // batches that failed or went back to the frontier
+	Busy     time.Duration `json:"busy_ns"`  // time spent fetching, waits for the rate limit included
+}
+
+func (s WorkerStats) String() string {
+	return fmt.Sprintf("fetcher %d: %d batches, %d titles, %d requests, %d errors, busy %s",
+		s.ID, s.Batches, s.Titles, s.Requests, s.Errors, s.Busy.Round(time.Millisecond))
+}
+
+// fetchWorker leases its own batches from the frontier, so a slow title only
+// holds up the batch it belongs to.
+type fetchWorker struct {
+	processor.BaseProcessor
+	id     int
+	client *APIClient
+
+	batches  atomic.Int64
+	titles   atomic.Int64
+	requests atomic.Int64
+	errors   atomic.Int64
+	busy     atomic.Int64 // nanoseconds
+}
+
+func newFetchWorker(id int, client *APIClient) *fetchWorker {
+	w := &fetchWorker{id: id, client: client}
+	w.Init(w)
+	return w
+}
+
+func (w *fetchWorker) RunningTask() {
+	a := w.client
+	if b := a.fetcher.Breaker(); b != nil && !b.Ready() {
+		// Upstream is down: leave the titles in the frontier until the breaker lets a probe through
+		time.Sleep(a.pollInterval)
+		return
+	}
+	titles, err := a.store.Frontier.Lease(a.batchSize)
+	if err != nil {
+		fmt.Printf("[APIClient] fetcher %d failed to lease from frontier: %v\n", w.id, err)
+		time.Sleep(a.pollInterval)
+		return
+	}
+	if len(titles) == 0 {
+		time.Sleep(a.pollInterval)
+		return
+	}
+
+	start := time.Now()
+	defer func() { w.busy.Add(int64(time.Since(start))) }()
+
+	// One titles= query only covers one wiki
+	var wikis []string
+	byWiki := make(map[string][]model.TitleQuery)
+	for _, t := range titles {
+		wiki := t.Wiki
+		if wiki == "" {
+			wiki = a.defaultWiki
+		}
+		if _, ok := byWiki[wiki]; !ok {
+			wikis = append(wikis, wiki)
+		}
+		byWiki[wiki] = append(byWiki[wiki], t)
+	}
+
+	for _, wiki := range wikis {
+		group := byWiki[wiki]
+		requests, err := a.fetchBatch(wiki, group)
+		w.requests.Add(int64(requests))
+		w.batches.Add(1)
+		if err != nil {
+			w.errors.Add(1)
+			fmt.Printf("[APIClient] fetcher %d: %v\n", w.id, err)
+			w.giveUp(group, err)
+			if errors.Is(err, rawdatafetcher.ErrLimiter) {
+				// Titles are back in the frontier; do not lease them again at once
+				time.Sleep(a.pollInterval)
+			}
+			continue
+		}
+		// Still in flight: the handler marks them done once their links are stored
+		w.titles.Add(int64(len(group)))
+	}
+}
+
+// giveUp records titles as failed once the retry policy gave up on them or the
+// server rejected them. Other errors (the rate limiter is unreachable, the
+// circuit breaker opened) say nothing about the titles, so they go back to the
+// frontier.
+func (w *fetchWorker) giveUp(group []model.TitleQuery, err error) {
+	var exhausted *retry.ExhaustedError
+	final := (errors.As(err, &exhausted) || retry.IsPermanent(err)) &&
+		!errors.Is(err, rawdatafetcher.ErrBreakerOpen) && !errors.Is(err, rawdatafetcher.ErrLimiter)
+	for _, t := range group {
+		var ferr error
+		if final {
+			ferr = w.client.store.Frontier.Fail(t, err)
+		} else {
+			// Give them back so they are retried; a crash instead lets the lease expire
+			ferr = w.client.store.Frontier.Release(t)
+		}
+		if ferr != nil {
+			fmt.Printf("[APIClient] Failed to give up %s: %v\n", t.Title, ferr)
+		}
+	}
+}
+
+func (w *fetchWorker) stats() WorkerStats {
+	return WorkerStats{
+		ID:       w.id,
+		Batches:  w.batches.Load(),
+		Titles:   w.titles.Load(),
+		Requests: w.requests.Load(),
+		Errors:   w.errors.Load(),
+		Busy:     time.Duration(w.busy.Load()),
+	}
+}
@@ -0,0 +1,120 @@
+package apiclient
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+	"wikicrawler/internal/infra"
+	"wikicrawler/internal/infra/frontier"
+	"wikicrawler/internal/model"
+	"wikicrawler/internal/utils/ratelimit"
+	"wikicrawler/internal/utils/retry"
+)
+
+// newTestClient is an APIClient with one worker on an in-memory frontier,
+// crawling the api.php at endpoint.
+func newTestClient(endpoint string, f frontier.Frontier, batchSize int) *APIClient {
+	store := &infra.WikiStore{Frontier: f, RawDataQ: make(chan model.RawDataWiki, 1000)}
+	return NewAPIClient(store, 5*time.Second, time.Minute, 10, 10, time.Millisecond, endpoint, batchSize, 1,
+		ratelimit.NewTokenBucket(0, 1),
+		retry.Exponential{Base: time.Millisecond, Max: 5 * time.Millisecond, MaxAttempts: 3},
+		0, 0)
+}
+
+type failingLimiter struct{ calls atomic.Int64 }
+
+func (l *failingLimiter) Wait(host, action string) error {
+	l.calls.Add(1)
+	return errors.New("redis: connection refused")
+}
+
+func pushTitles(t *testing.T, f frontier.Frontier, wiki string, titles ...string) {
+	t.Helper()
+	for _, title := range titles {
+		if _, err := f.Push(model.TitleQuery{Wiki: wiki, Title: title, Seed: title}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLimiterErrorReleasesTitles(t *testing.T) {
+	var hits atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hits.Add(1) }))
+	defer srv.Close()
+
+	f := frontier.NewMemoryFrontier(time.Minute)
+	pushTitles(t, f, srv.URL, "A", "B")
+	a := newTestClient(srv.URL, f, 50)
+	limiter := &failingLimiter{}
+	a.SetLimiter(limiter)
+
+	a.workers[0].RunningTask()
+
+	if n := limiter.calls.Load(); n != 1 {
+		t.Errorf("limiter asked %d times, want 1 (not retried)", n)
+	}
+	if n := hits.Load(); n != 0 {
+		t.Errorf("%d request(s) sent past a failing limiter", n)
+	}
+	counts, _ := f.Counts()
+	if counts[frontier.StatePending] != 2 || counts[frontier.StateFailed] != 0 {
+		t.Errorf("frontier %v, want both titles pending again", counts)
+	}
+}
+
+func TestExhaustedRetriesFailTitles(t *testing.T) {
+	var hits atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	f := frontier.NewMemoryFrontier(time.Minute)
+	pushTitles(t, f, srv.URL, "A", "B")
+	a := newTestClient(srv.URL, f, 50)
+
+	a.workers[0].RunningTask()
+
+	if n := hits.Load(); n != 3 {
+		t.Errorf("%d request(s), want 3 attempts", n)
+	}
+	counts, _ := f.Counts()
+	if counts[frontier.StateFailed] != 2 {
+		t.Errorf("frontier %v, want both titles failed", counts)
+	}
+}
+
+func TestTitleWithoutWikiKeepsItsLeasedKey(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"query":{"pages":{"1":{"pageid":1,"ns":0,"title":"A","links":[{"ns":0,"title":"B"}]}}}}`))
+	}))
+	defer srv.Close()
+
+	// Queued by an older Redis frontier, before titles carried their wiki
+	f := frontier.NewMemoryFrontier(time.Minute)
+	pushTitles(t, f, "", "A")
+	a := newTestClient(srv.URL, f, 50)
+
+	a.workers[0].RunningTask()
+
+	var data model.RawDataWiki
+	select {
+	case data = <-a.store.RawDataQ:
+	default:
+		t.Fatal("nothing fetched")
+	}
+	if data.TitleQ.Wiki != "" || data.Wiki != srv.URL {
+		t.Fatalf("TitleQ.Wiki = %q, Wiki = %q; want the leased key and %q", data.TitleQ.Wiki, data.Wiki, srv.URL)
+	}
+	if err := f.Done(data.TitleQ); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := f.State("", "A"); state != frontier.StateDone {
+		t.Errorf("state %q after the ack, want done", state)
+	}
+}
@@ -96,6 +96,11 @@ func (s *CDCClient) Close() error {
 	return nil
 }
 
+// Ready reports whether replication was started and the connection is still open.
+func (s *CDCClient) Ready() bool {
+	return s.IsRunning() && s.PostGresConn != nil && !s.PostGresConn.IsClosed()
+}
+
 func (s *CDCClient) RegisterHandler(h MessageHandler) {
 	s.handler = h
 }
@@ -54,6 +54,11 @@ func (c *CDCManager) Open() error {
 	return nil
 }
 
+// Ready reports whether the replication slot is streaming changes.
+func (c *CDCManager) Ready() bool {
+	return c.cdcclient.Ready()
+}
+
 func (c *CDCManager) Close() error {
 	if err := c.cdcclient.Close(); err != nil {
 		return err
@@ -0,0 +1,129 @@
+package graphquery
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"wikicrawler/internal/infra/graphstore"
+)
+
+type Stats struct {
+	Titles  int64 `json:"titles"`
+	Pairs   int64 `json:"pairs"`
+	Missing int64 `json:"missing"` // titles MediaWiki has no page for
+	Invalid int64 `json:"invalid"`
+}
+
+// GetStats counts the nodes and edges crawled so far.
+func GetStats(g graphstore.Graph) (Stats, error) {
+	c, err := g.Counts()
+	if err != nil {
+		return Stats{}, err
+	}
+	return Stats{
+		Titles:  c.Titles,
+		Pairs:   c.Pairs,
+		Missing: c.ByStatus[graphstore.StatusMissing],
+		Invalid: c.ByStatus[graphstore.StatusInvalid],
+	}, nil
+}
+
+// ShortestPath runs a level-by-level BFS over the edges, one Neighbors call per level, and
+// returns the title names from src to dst of one wiki. It gives up after maxHops levels.
+// src and dst may be aliases of the canonical titles.
+func ShortestPath(g graphstore.Graph, wiki, src, dst string, maxHops int) ([]string, error) {
+	srcID, err := graphstore.ResolveTitle(g, wiki, src)
+	if err != nil {
+		return nil, err
+	}
+	dstID, err := graphstore.ResolveTitle(g, wiki, dst)
+	if err != nil {
+		return nil, err
+	}
+	if srcID == dstID {
+		return []string{src}, nil
+	}
+
+	parent := map[string]string{srcID: ""}
+	frontier := []string{srcID}
+	for hop := 0; hop < maxHops && len(frontier) > 0; hop++ {
+		neighbors, err := g.Neighbors(frontier)
+		if err != nil {
+			return nil, err
+		}
+
+		var next []string
+		for _, from := range frontier {
+			for _, to := range neighbors[from] {
+				if _, seen := parent[to]; seen {
+					continue
+				}
+				parent[to] = from
+				if to == dstID {
+					return buildPath(g, parent, dstID)
+				}
+				next = append(next, to)
+			}
+		}
+		frontier = next
+	}
+	return nil, fmt.Errorf("[GraphQuery] no path from '%s' to '%s' within %d hops", src, dst, maxHops)
+}
+
+func buildPath(g graphstore.Graph, parent map[string]string, dstID string) ([]string, error) {
+	var ids []string
+	for id := dstID; id != ""; id = parent[id] {
+		ids = append([]string{id}, ids...)
+	}
+	names, err := g.TitleNames(ids)
+	if err != nil {
+		return nil, err
+	}
+	path := make([]string, len(ids))
+	for i, id := range ids {
+		path[i] = names[id]
+	}
+	return path, nil
+}
+
+// Export streams every edge as "wiki,src,dst" CSV rows or {"wiki","src","dst"} JSON lines.
+func Export(g graphstore.Graph, w io.Writer, format string) (int, error) {
+	n := 0
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"wiki", "src", "dst"}); err != nil {
+			return 0, err
+		}
+		err := g.ForEachEdge(func(wiki, src, dst string) error {
+			n++
+			return cw.Write([]string{wiki, src, dst})
+		})
+		cw.Flush()
+		if err == nil {
+			err = cw.Error()
+		}
+		return n, err
+
+	case "jsonl":
+		enc := json.NewEncoder(w)
+		err := g.ForEachEdge(func(wiki, src, dst string) error {
+			n++
+			return enc.Encode(struct {
+				Wiki string `json:"wiki"`
+				Src  string `json:"src"`
+				Dst  string `json:"dst"`
+			}{wiki, src, dst})
+		})
+		return n, err
+
+	default:
+		return 0, fmt.Errorf("[GraphQuery] unknown export format %q (want csv or jsonl)", format)
+	}
+}
+
+// WithinHops lists the titles of a wiki discovered from seed at most hops links away.
+func WithinHops(g graphstore.Graph, wiki, seed string, hops int) ([]string, error) {
+	return g.TitlesWithinHops(wiki, seed, hops)
+}
@@ -0,0 +1,105 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Check reports whether a component is healthy, with details for the output.
+type Check func() (healthy bool, details any)
+
+// Server answers GET /healthz with the result of every registered check:
+// 200 when all are healthy, 503 otherwise.
+type Server struct {
+	srv    *http.Server
+	mu     sync.Mutex
+	checks map[string]Check
+}
+
+type Component struct {
+	Healthy bool `json:"healthy"`
+	Details any  `json:"details,omitempty"`
+}
+
+type Report struct {
+	Status     string               `json:"status"` // "ok" or "degraded"
+	Time       time.Time            `json:"time"`
+	Components map[string]Component `json:"components"`
+}
+
+func NewServer(addr string) *Server {
+	s := &Server{checks: make(map[string]Check)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.handle)
+	s.srv = &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	return s
+}
+
+// Register adds (or replaces) the check of a component.
+func (s *Server) Register(name string, check Check) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checks[name] = check
+}
+
+// Report runs every check.
+func (s *Server) Report() Report {
+	// Copy under the lock: Register may add checks while they run
+	s.mu.Lock()
+	names := make([]string, 0, len(s.checks))
+	checks := make(map[string]Check, len(s.checks))
+	for name, check := range s.checks {
+		names = append(names, name)
+		checks[name] = check
+	}
+	s.mu.Unlock()
+	sort.Strings(names)
+
+	r := Report{Status: "ok", Time: time.Now(), Components: make(map[string]Component, len(names))}
+	for _, name := range names {
+		healthy, details := checks[name]()
+		r.Components[name] = Component{Healthy: healthy, Details: details}
+		if !healthy {
+			r.Status = "degraded"
+		}
+	}
+	return r
+}
+
+func (s *Server) handle(w http.ResponseWriter, _ *http.Request) {
+	r := s.Report()
+	w.Header().Set("Content-Type", "application/json")
+	if r.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(r)
+}
+
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.srv.Addr)
+	if err != nil {
+		return fmt.Errorf("[Health] failed to listen on %s: %w", s.srv.Addr, err)
+	}
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("[Health] server stopped: %v\n", err)
+		}
+	}()
+	fmt.Printf("[Health] serving /healthz on %s\n", s.srv.Addr)
+	return nil
+}
+
+func (s *Server) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.srv.Shutdown(ctx)
+}
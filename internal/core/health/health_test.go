@@ -0,0 +1,47 @@
+package health
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	s := NewServer("")
+	s.Register("db", func() (bool, any) { return true, nil })
+	s.Register("breaker", func() (bool, any) { return false, "open" })
+
+	r := s.Report()
+	if r.Status != "degraded" {
+		t.Errorf("status = %q, want degraded", r.Status)
+	}
+	if c := r.Components["breaker"]; c.Healthy || c.Details != "open" {
+		t.Errorf("breaker = %+v", c)
+	}
+	if !r.Components["db"].Healthy {
+		t.Errorf("db = %+v", r.Components["db"])
+	}
+}
+
+// Run with -race: Register must not race with a Report in progress.
+func TestReportWhileRegistering(t *testing.T) {
+	s := NewServer("")
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			s.Register(fmt.Sprint("c", i), func() (bool, any) { return true, nil })
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			s.Report()
+		}
+	}()
+	wg.Wait()
+	if n := len(s.Report().Components); n != 200 {
+		t.Errorf("%d components, want 200", n)
+	}
+}
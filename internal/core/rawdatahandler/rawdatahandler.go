@@ -1,31 +1,76 @@
 package rawdatahandler
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"strings"
+	"sync/atomic"
 	"time"
-	"wikicrawler/internal/infra"
+	"wikicrawler/internal/core/apiclient/api"
+	"wikicrawler/internal/infra/frontier"
+	"wikicrawler/internal/infra/graphstore"
 	"wikicrawler/internal/model"
 	"wikicrawler/internal/utils/processor"
+	"wikicrawler/internal/utils/spill"
 	"wikicrawler/internal/utils/workers"
 	tasks "wikicrawler/internal/utils/workers/task"
-
-	"github.com/google/uuid"
 )
 
 type RawDataHandler struct {
 	processor.BaseProcessor
-	workerPool *workers.WorkerPool
-	store      *infra.WikiStore
+	workerPool  *workers.WorkerPool
+	graph       graphstore.GraphStore
+	rawDataQ    <-chan model.RawDataWiki
+	frontier    frontier.Frontier            // titles found on a page are queued here
+	maxDepth    int                          // deepest hop whose links are still queued, 0 = unlimited
+	pushTimeout time.Duration                // wait for a free worker before spilling/dropping, 0 = forever
+	spill       *spill.Queue[spilledRawData] // nil = no overflow to disk
+	spilled     atomic.Int64
+	dropped     atomic.Int64
+}
+
+// spilledRawData is a RawDataWiki on disk; Err is derived from the page again.
+type spilledRawData struct {
+	TitleQ   model.TitleQuery
+	Wiki     string
+	LinksRes model.WikiLinksResponse
+}
+
+// Stats counts the pages that did not go straight to a worker.
+type Stats struct {
+	Queued  int   `json:"queued"`  // waiting for a worker
+	Spilled int64 `json:"spilled"` // written to the spill file since start
+	OnDisk  int64 `json:"on_disk"` // still in the spill file
+	Dropped int64 `json:"dropped"` // lost: no worker in time and no spill file
 }
 
-func NewRawDataHandler(store *infra.WikiStore, nworkers, ntasks int) *RawDataHandler {
+// NewRawDataHandler builds the handler that stores the pages read from
+// rawDataQ in graph. spillPath, when not empty, is a file that takes the pages
+// the workers cannot take within pushTimeout. A spilled page is read from it
+// once: if the process stops before it is stored, its title is still leased
+// and is fetched again when the lease runs out.
+func NewRawDataHandler(graph graphstore.GraphStore, rawDataQ <-chan model.RawDataWiki, front frontier.Frontier,
+	nworkers, ntasks, maxDepth int, pushTimeout time.Duration, spillPath string) (*RawDataHandler, error) {
 	r := &RawDataHandler{}
 	r.workerPool = workers.NewWorkerPool(nworkers, ntasks)
-	r.store = store
+	r.graph = graph
+	r.rawDataQ = rawDataQ
+	r.frontier = front
+	r.maxDepth = maxDepth
+	r.pushTimeout = pushTimeout
+	if spillPath != "" {
+		q, err := spill.Open[spilledRawData](spillPath)
+		if err != nil {
+			return nil, err
+		}
+		if n := q.Len(); n > 0 {
+			log.Printf("[RawDataHandler] %d spilled page(s) left from the last run", n)
+		}
+		r.spill = q
+	}
 	r.Init(r)
-	return r
+	return r, nil
 }
 
 func (r *RawDataHandler) Start() error {
@@ -37,97 +82,526 @@ func (r *RawDataHandler) Start() error {
 }
 
 func (r *RawDataHandler) Stop() error {
-	r.workerPool.Stop()
+	// The loop is the only writer of the spill file: it has to be over before
+	// the file is closed, or the page it is dispatching counts as dropped
 	if err := r.BaseProcessor.Stop(); err != nil {
 		return err
 	}
+	r.Wait()
+	r.workerPool.Stop()
+	s := r.Stats()
+	log.Printf("[RawDataHandler] %d page(s) spilled (%d still on disk), %d dropped", s.Spilled, s.OnDisk, s.Dropped)
+	if r.spill != nil {
+		return r.spill.Close()
+	}
 	return nil
 }
 
+func (r *RawDataHandler) Stats() Stats {
+	s := Stats{
+		Queued:  r.workerPool.Tasks.Size(),
+		Spilled: r.spilled.Load(),
+		Dropped: r.dropped.Load(),
+	}
+	if r.spill != nil {
+		s.OnDisk = r.spill.Len()
+	}
+	return s
+}
+
 func (r *RawDataHandler) RunningTask() {
-	start := time.Now()
-	data := <-r.store.RawDataQ
+	// Spilled pages go back to the workers whenever they have room
+	if r.spill != nil && r.spill.Len() > 0 {
+		r.unspill()
+	}
+
+	select {
+	case data := <-r.rawDataQ:
+		r.dispatch(data)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// dispatch hands data to a worker, waiting up to pushTimeout, then spills it
+// to disk; it is only dropped when there is no spill file or writing fails.
+func (r *RawDataHandler) dispatch(data model.RawDataWiki) {
 	task := tasks.NewTask(r.rawdataHandler, data)
-	ok := r.workerPool.Tasks.Push(task)
+	if r.workerPool.Tasks.PushTimeout(task, r.pushTimeout) {
+		return
+	}
 
-	if !ok {
-		fmt.Printf("[RawDataHandler] failed to queue data handle task %d\n", r.workerPool.Tasks.Size())
-		time.Sleep(10 * time.Millisecond)
+	if r.spill != nil {
+		err := r.spill.Push(spilledRawData{TitleQ: data.TitleQ, Wiki: data.Wiki, LinksRes: data.LinksRes})
+		if err == nil {
+			r.spilled.Add(1)
+			return
+		}
+		log.Printf("[RawDataHandler] %v", err)
 	}
-	elapsed := time.Since(start)
-	if elapsed < 2*time.Millisecond {
-		time.Sleep(2*time.Millisecond - elapsed)
+	r.dropped.Add(1)
+	log.Printf("[RawDataHandler] ❌ dropped the links of '%s' (%s): %d tasks queued",
+		data.TitleQ.Title, data.TitleQ.Wiki, r.workerPool.Tasks.Size())
+	// Fetched again later instead of waiting for a lease that is kept alive
+	if err := r.frontier.Release(data.TitleQ); err != nil {
+		log.Printf("[RawDataHandler] Failed to release '%s': %v", data.TitleQ.Title, err)
+	}
+}
+
+// unspill moves spilled pages to the workers until they are busy again.
+func (r *RawDataHandler) unspill() {
+	for r.workerPool.Tasks.Size() < r.workerPool.Tasks.Capacity {
+		item, ok, err := r.spill.Pop()
+		if err != nil {
+			log.Printf("[RawDataHandler] %v", err)
+			return
+		}
+		if !ok {
+			return
+		}
+		data := model.RawDataWiki{TitleQ: item.TitleQ, Wiki: item.Wiki, LinksRes: item.LinksRes}
+		for _, page := range item.LinksRes.Query.Pages {
+			data.Err = api.PageError(page)
+		}
+		if !r.workerPool.Tasks.Push(tasks.NewTask(r.rawdataHandler, data)) {
+			// Lost the race for the last free slot; back to the end of the file
+			if err := r.spill.Push(item); err != nil {
+				r.dropped.Add(1)
+				log.Printf("[RawDataHandler] ❌ dropped the links of '%s': %v", item.TitleQ.Title, err)
+				if err := r.frontier.Release(item.TitleQ); err != nil {
+					log.Printf("[RawDataHandler] Failed to release '%s': %v", item.TitleQ.Title, err)
+				}
+			}
+			return
+		}
 	}
 }
+
 func (r *RawDataHandler) rawdataHandler(data model.RawDataWiki) {
-	result := data.LinksRes
-	// --- Ensure source title exists (atomic with SETNX) ---
+	leased := data.TitleQ // frontier key: the title as requested, before it is resolved to its canonical name
+	if data.TitleQ.Wiki == "" {
+		// Queued without a wiki (older Redis frontiers): stored under the one it was fetched from
+		data.TitleQ.Wiki, data.TitleQ.ID = data.Wiki, ""
+	}
+
+	// One transaction per page: a crash or error never leaves titles without their edges.
+	// A frontier that can join it queues the children and marks the page done in
+	// the same transaction; otherwise both follow the commit, and a crash in
+	// between only refetches the page once its lease expires.
+	var next crawlNext
+	var queued bool
+	err := r.graph.WithTx(context.Background(), func(tx graphstore.Graph) error {
+		var err error
+		queued = false
+		if next, err = (&pageTx{g: tx, maxDepth: r.maxDepth}).store(data); err != nil {
+			return err
+		}
+		front, ok := r.frontierInTx(tx)
+		if !ok {
+			return nil
+		}
+		if err := next.queue(front); err != nil {
+			return err
+		}
+		if err := front.Done(leased); err != nil {
+			return err
+		}
+		queued = true
+		return nil
+	})
+	if err != nil {
+		log.Printf("[RawDataHandler] Failed to store '%s' (%s): %v", data.TitleQ.Title, data.TitleQ.Wiki, err)
+		if err := r.frontier.Release(leased); err != nil {
+			log.Printf("[RawDataHandler] Failed to release '%s': %v", leased.Title, err)
+		}
+		return
+	}
+	if queued {
+		return
+	}
+
+	if err := next.queue(r.frontier); err != nil {
+		// Not done: the page is fetched again and queues them then
+		log.Printf("[RawDataHandler] Failed to queue the titles linked from '%s': %v", data.TitleQ.Title, err)
+		if err := r.frontier.Release(leased); err != nil {
+			log.Printf("[RawDataHandler] Failed to release '%s': %v", leased.Title, err)
+		}
+		return
+	}
+	if err := r.frontier.Done(leased); err != nil {
+		log.Printf("[RawDataHandler] Failed to mark '%s' done: %v", leased.Title, err)
+	}
+}
+
+// crawlNext is what a stored page adds to the frontier.
+type crawlNext struct {
+	push    []model.TitleQuery // linked titles to crawl; the frontier skips the ones it knows
+	requeue []model.TitleQuery // titles new or closer to a seed; moved up if still pending
+}
+
+func (n crawlNext) queue(f frontier.Frontier) error {
+	if len(n.push) > 0 {
+		if _, err := f.Push(n.push...); err != nil {
+			return err
+		}
+	}
+	if len(n.requeue) > 0 {
+		if _, err := f.Requeue(n.requeue...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// frontierInTx returns the frontier inside tx when it can join the graph's transaction.
+func (r *RawDataHandler) frontierInTx(tx graphstore.Graph) (frontier.Frontier, bool) {
+	if tf, ok := r.frontier.(frontier.TxFrontier); ok {
+		return tf.InTx(tx)
+	}
+	return nil, false
+}
+
+// pageTx is the graph of one page's transaction. Its methods return every
+// error, since a failed statement aborts the whole transaction anyway.
+type pageTx struct {
+	g        graphstore.Graph
+	maxDepth int // deepest hop whose links are still queued, 0 = unlimited
+}
+
+// store writes the title, aliases, linked titles and edges of data and returns
+// the linked titles that should be crawled next.
+func (p *pageTx) store(data model.RawDataWiki) (crawlNext, error) {
+	// --- Resolve redirects / normalization: the canonical title is the node ---
+	if canonical := data.LinksRes.Canonical(data.TitleQ.Title); canonical != data.TitleQ.Title {
+		if err := p.mergeIntoCanonical(&data.TitleQ, canonical); err != nil {
+			return crawlNext{}, fmt.Errorf("failed to resolve '%s' → '%s': %w", data.TitleQ.Title, canonical, err)
+		}
+	}
+
+	// --- Ensure source title exists, with the (seed, depth) it was leased at ---
 	if data.TitleQ.ID == "" {
-		data.TitleQ.ID = uuid.New().String()
-		if err := r.store.TitlesTable.Insert(map[string]interface{}{
-			"title_id": data.TitleQ.ID,
-			"name":     data.TitleQ.Title,
-		}); err != nil {
-			log.Printf("[RawDataHandler] Failed to insert title %s: %v", data.TitleQ.Title, err)
-			return
-		} else {
-			log.Printf("[RawDataHandler] Success to insert title '%s' to titles", data.TitleQ.Title)
+		data.TitleQ.ID = model.TitleID(data.TitleQ.Wiki, data.TitleQ.Title)
+	}
+	// Seed crawled before (or an earlier plcontinue page of it) → its stored ID is reused
+	ids, _, err := p.g.UpsertTitles([]model.TitleQuery{data.TitleQ})
+	if err != nil {
+		return crawlNext{}, fmt.Errorf("failed to upsert title: %w", err)
+	}
+	data.TitleQ.ID = ids[data.TitleQ.Title]
+	if err := p.recordAliases(data); err != nil {
+		return crawlNext{}, err
+	}
 
+	// --- Missing / invalid titles have no links: record why and stop ---
+	if data.Err != nil {
+		status := graphstore.StatusMissing
+		if errors.Is(data.Err, api.ErrInvalidTitle) {
+			status = graphstore.StatusInvalid
+		}
+		log.Printf("[RawDataHandler] %v", data.Err)
+		if err := p.g.SetTitleStatus(data.TitleQ.ID, status); err != nil {
+			return crawlNext{}, err
 		}
+		return crawlNext{}, nil
 	}
 
 	// --- Process linked titles ---
-	for _, page := range result.Query.Pages {
+	return p.storeLinks(data)
+}
+
+// storeLinks upserts the linked titles and the edges of data. Every seed the
+// page was found from gives the links a depth one hop further from that seed,
+// so "within N hops" is right for every seed and not only the closest one.
+// Links to known aliases become edges to their canonical title, which was
+// crawled itself.
+//
+// Links within maxDepth of some seed are pushed, all of them and not only the
+// new ones: the frontier skips the titles it knows, and queues a title stored
+// by a page whose frontier push never happened. A linked title that was
+// crawled before and is new to a seed (or closer to it) is not fetched again:
+// carry follows its stored edges instead. Links one hop past maxDepth are
+// stored (with their edge) but not crawled.
+func (p *pageTx) storeLinks(data model.RawDataWiki) (crawlNext, error) {
+	aliases, err := p.resolveLinks(data)
+	if err != nil {
+		return crawlNext{}, err
+	}
+	seen := make(map[string]bool)
+	var links, targets []string // targets are the links that are not aliases
+	for _, page := range data.LinksRes.Query.Pages {
 		for _, link := range page.Links {
-			if link.Ns != 0 {
+			if link.Ns != 0 || seen[link.Title] {
 				continue
 			}
+			seen[link.Title] = true
+			links = append(links, link.Title)
+			if _, ok := aliases[link.Title]; !ok {
+				targets = append(targets, link.Title)
+			}
+		}
+	}
+	if len(links) == 0 {
+		return crawlNext{}, nil
+	}
+
+	seeds, err := p.g.TitleSeeds(data.TitleQ.ID)
+	if err != nil {
+		return crawlNext{}, err
+	}
+	var expand, beyond []model.TitleQuery
+	for seed, depth := range seeds {
+		if p.maxDepth > 0 && depth > p.maxDepth {
+			continue // never crawled from this seed
+		}
+		from := model.TitleQuery{Wiki: data.TitleQ.Wiki, Depth: depth, Seed: seed}
+		for _, name := range targets {
+			child := from.Child(name)
+			if p.maxDepth <= 0 || depth < p.maxDepth {
+				expand = append(expand, child)
+			} else {
+				beyond = append(beyond, child)
+			}
+		}
+	}
 
-			titleID := uuid.New().String()
-
-			if err := r.store.TitlesTable.Insert(map[string]interface{}{
-				"title_id": titleID,
-				"name":     link.Title,
-			}); err != nil {
-				if strings.Contains(err.Error(), "duplicate key") {
-					// Title already exists → fetch its ID
-					existing, getErr := r.store.TitlesTable.GetRecordByKey("name", link.Title)
-					if getErr != nil {
-						log.Printf("[RawDataHandler] Failed to get existing title '%s': %v", link.Title, getErr)
-						continue
-					}
+	ids, reached, err := p.g.UpsertTitles(expand)
+	if err != nil {
+		return crawlNext{}, err
+	}
+	if len(beyond) > 0 {
+		// Closer or not, they are not crawled
+		more, _, err := p.g.UpsertTitles(beyond)
+		if err != nil {
+			return crawlNext{}, err
+		}
+		for name, id := range more {
+			ids[name] = id
+		}
+	}
 
-					if id, ok := existing["title_id"].(string); ok {
-						titleID = id
-						log.Printf("[RawDataHandler] Found existing title '%s' with ID %s", link.Title, titleID)
-					} else {
-						log.Printf("[RawDataHandler] Invalid data for existing title '%s'", link.Title)
-						continue
-					}
-				} else {
-					log.Printf("[RawDataHandler] Failed to insert title '%s': %v", link.Title, err)
+	dsts := make([]string, 0, len(links))
+	for _, name := range links {
+		if id, ok := aliases[name]; ok {
+			dsts = append(dsts, id)
+		} else if id, ok := ids[name]; ok {
+			dsts = append(dsts, id)
+		}
+	}
+	npairs, err := p.g.AddEdges(data.TitleQ.ID, dsts)
+	if err != nil {
+		return crawlNext{}, err
+	}
+
+	var changed []model.TitleQuery
+	for _, child := range expand {
+		if reached[child.Title] {
+			child.ID = ids[child.Title]
+			changed = append(changed, child)
+		}
+	}
+	carried, err := p.carry(changed)
+	if err != nil {
+		return crawlNext{}, err
+	}
+	log.Printf("[RawDataHandler] '%s': %d link(s) from %d seed(s), %d title(s) new or closer to a seed, %d more through stored links, %d new pair(s)",
+		data.TitleQ.Title, len(links), len(seeds), len(reached), len(carried), npairs)
+
+	// One frontier item per title, at its closest seed. The ones new or closer to
+	// a seed are requeued, which moves them up if they are still pending.
+	closest := make(map[string]model.TitleQuery)
+	for _, child := range append(expand, carried...) {
+		if prev, ok := closest[child.Title]; !ok || child.Depth < prev.Depth {
+			closest[child.Title] = child
+		}
+	}
+	var next crawlNext
+	for _, name := range targets {
+		child, ok := closest[name]
+		if !ok {
+			continue
+		}
+		delete(closest, name)
+		child.ID = ids[name]
+		next.push = append(next.push, child)
+		if reached[name] {
+			next.requeue = append(next.requeue, child)
+		}
+	}
+	for _, child := range closest {
+		if p.maxDepth <= 0 || child.Depth <= p.maxDepth {
+			next.requeue = append(next.requeue, child)
+		}
+	}
+	return next, nil
+}
+
+// carry records the seed depths of changed, titles that are new to a seed or
+// closer to it, on the titles they link to, hop by hop over the stored edges:
+// a title crawled before already has its links stored, so they are not fetched
+// again for the new seed. A title without edges (not crawled yet) stops it;
+// its links get every seed when it is crawled. It returns the titles it
+// changed, one per title and seed.
+//
+// The stored links are the ones of the page being crawled when its
+// transaction took the row lock of the title, so a page stored concurrently
+// either sees the new seed or has its edges seen here.
+func (p *pageTx) carry(changed []model.TitleQuery) ([]model.TitleQuery, error) {
+	var carried []model.TitleQuery
+	for len(changed) > 0 {
+		from := make(map[string][]model.TitleQuery) // src ID -> its (seed, depth) to carry
+		var srcs []string
+		for _, q := range changed {
+			if p.maxDepth > 0 && q.Depth > p.maxDepth {
+				continue // never crawled from this seed
+			}
+			if _, ok := from[q.ID]; !ok {
+				srcs = append(srcs, q.ID)
+			}
+			from[q.ID] = append(from[q.ID], q)
+		}
+		if len(srcs) == 0 {
+			break
+		}
+		neighbors, err := p.g.Neighbors(srcs)
+		if err != nil {
+			return nil, err
+		}
+		var dsts []string
+		for _, ids := range neighbors {
+			dsts = append(dsts, ids...)
+		}
+		if len(dsts) == 0 {
+			break
+		}
+		names, err := p.g.TitleNames(dsts)
+		if err != nil {
+			return nil, err
+		}
+
+		// One row per (title, seed), at its lowest depth
+		type key struct{ id, seed string }
+		next := make(map[key]model.TitleQuery)
+		for _, src := range srcs {
+			for _, dst := range neighbors[src] {
+				name, ok := names[dst]
+				if !ok {
 					continue
 				}
-			} else {
-				log.Printf("[RawDataHandler] Success to insert title '%s' to titles", link.Title)
-				r.store.TitlsToQueryQ <- model.TitleQuery{Title: link.Title, ID: titleID}
+				for _, q := range from[src] {
+					child := q.Child(name)
+					child.ID = dst
+					if prev, ok := next[key{dst, q.Seed}]; !ok || child.Depth < prev.Depth {
+						next[key{dst, q.Seed}] = child
+					}
+				}
+			}
+		}
+		children := make([]model.TitleQuery, 0, len(next))
+		for _, child := range next {
+			children = append(children, child)
+		}
+		_, reached, err := p.g.UpsertTitles(children)
+		if err != nil {
+			return nil, err
+		}
+		changed = changed[:0]
+		for _, child := range children {
+			if reached[child.Title] {
+				changed = append(changed, child)
 			}
+		}
+		carried = append(carried, changed...)
+	}
+	return carried, nil
+}
 
-			// --- Build relationship (also atomic) ---
-			pairID := uuid.New().String()
+// mergeIntoCanonical points q at the canonical title MediaWiki answered with.
+// A node stored under the requested (alias) name is folded into the canonical
+// node, edges, seed depths and aliases included, which is created under its
+// own deterministic ID when it is missing.
+func (p *pageTx) mergeIntoCanonical(q *model.TitleQuery, canonical string) error {
+	aliasID := q.ID
+	if aliasID == "" {
+		id, err := p.g.TitleIDByName(q.Wiki, q.Title)
+		if err != nil && !errors.Is(err, graphstore.ErrNotFound) {
+			return err
+		}
+		aliasID = id
+	}
 
-			if err := r.store.PairsTable.Insert(map[string]interface{}{
-				"pair_id":   pairID,
-				"title_src": data.TitleQ.ID,
-				"title_dst": titleID,
-			}); err != nil {
-				log.Printf("[RawDataHandler] Failed to insert pair (%s → %s): %v",
-					data.TitleQ.Title, link.Title, err)
-				continue
-			} else {
-				log.Printf("[RawDataHandler] Success to insert pair (%s → %s) to pairs",
-					data.TitleQ.Title, link.Title)
+	canonicalID, err := p.g.TitleIDByName(q.Wiki, canonical)
+	switch {
+	case err == nil:
+	case errors.Is(err, graphstore.ErrNotFound):
+		if aliasID == "" {
+			break // neither is stored → inserted as a new title below
+		}
+		canonicalID = model.TitleID(q.Wiki, canonical)
+		node := *q
+		node.ID, node.Title = canonicalID, canonical
+		if _, err := p.g.InsertTitle(node); err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+
+	if aliasID != "" && aliasID != canonicalID {
+		if err := p.g.RepointTitle(aliasID, canonicalID); err != nil {
+			return err
+		}
+		if err := p.g.DeleteTitle(aliasID); err != nil {
+			return err
+		}
+		log.Printf("[RawDataHandler] Merged '%s' into '%s'", q.Title, canonical)
+	}
+
+	q.Title, q.ID = canonical, canonicalID
+	return nil
+}
+
+// recordAliases stores every normalized and redirect form in data that leads to
+// the (already canonical) title data.TitleQ.
+func (p *pageTx) recordAliases(data model.RawDataWiki) error {
+	q := data.TitleQ
+	record := func(alias, kind string) error {
+		if alias == "" || alias == q.Title {
+			return nil
+		}
+		if err := p.g.UpsertAlias(q.Wiki, alias, q.ID, kind); err != nil {
+			return fmt.Errorf("failed to record alias '%s': %w", alias, err)
+		}
+		return nil
+	}
+	for _, n := range data.LinksRes.Query.Normalized {
+		if err := record(n.From, graphstore.AliasNormalized); err != nil {
+			return err
+		}
+	}
+	for _, rd := range data.LinksRes.Query.Redirects {
+		if err := record(rd.From, graphstore.AliasRedirect); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveLinks returns alias -> canonical title_id for the links of data that
+// are known redirects or normalized forms.
+func (p *pageTx) resolveLinks(data model.RawDataWiki) (map[string]string, error) {
+	var names []string
+	for _, page := range data.LinksRes.Query.Pages {
+		for _, link := range page.Links {
+			if link.Ns == 0 {
+				names = append(names, link.Title)
 			}
 		}
 	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	ids, err := p.g.ResolveAliases(data.TitleQ.Wiki, names)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve aliases: %w", err)
+	}
+	return ids, nil
 }
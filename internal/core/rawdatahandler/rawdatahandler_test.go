@@ -0,0 +1,329 @@
+package rawdatahandler
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+	"wikicrawler/internal/core/apiclient/api"
+	"wikicrawler/internal/infra/frontier"
+	"wikicrawler/internal/infra/graphstore"
+	"wikicrawler/internal/model"
+)
+
+func newTestHandler(t *testing.T, maxDepth int) (*RawDataHandler, *graphstore.MemoryGraphStore, *frontier.MemoryFrontier) {
+	t.Helper()
+	g := graphstore.NewMemoryGraphStore()
+	f := frontier.NewMemoryFrontier(time.Minute)
+	r, err := NewRawDataHandler(g, make(chan model.RawDataWiki), f, 1, 1, maxDepth, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, g, f
+}
+
+// page is the RawDataWiki of q with links in the main namespace.
+func page(q model.TitleQuery, links ...string) model.RawDataWiki {
+	p := model.WikiPage{Pageid: 1, Title: q.Title}
+	for _, l := range links {
+		p.Links = append(p.Links, model.WikiLink{Title: l})
+	}
+	data := model.RawDataWiki{TitleQ: q}
+	data.LinksRes.Query.Pages = map[string]model.WikiPage{"1": p}
+	return data
+}
+
+// lease pushes q and leases it, as a fetch worker would.
+func lease(t *testing.T, f frontier.Frontier, q model.TitleQuery) model.TitleQuery {
+	t.Helper()
+	if _, err := f.Push(q); err != nil {
+		t.Fatal(err)
+	}
+	items, err := f.Lease(1)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("Lease = %v, %v", items, err)
+	}
+	return items[0]
+}
+
+func wantState(t *testing.T, f *frontier.MemoryFrontier, title string, want frontier.State) {
+	t.Helper()
+	if got, ok := f.State("en", title); !ok || got != want {
+		t.Errorf("%s: state %q (known %v), want %q", title, got, ok, want)
+	}
+}
+
+func TestPageIsDoneOnlyOnceStored(t *testing.T) {
+	r, _, f := newTestHandler(t, 0)
+	seed := lease(t, f, model.TitleQuery{Wiki: "en", Title: "A", Seed: "A"})
+	wantState(t, f, "A", frontier.StateInFlight)
+
+	r.rawdataHandler(page(seed, "B", "C"))
+	wantState(t, f, "A", frontier.StateDone)
+	wantState(t, f, "B", frontier.StatePending)
+	wantState(t, f, "C", frontier.StatePending)
+
+	// A late duplicate of the page (e.g. its lease expired) changes nothing
+	if _, err := f.Lease(2); err != nil {
+		t.Fatal(err)
+	}
+	r.rawdataHandler(page(seed, "B", "C"))
+	wantState(t, f, "A", frontier.StateDone)
+	wantState(t, f, "B", frontier.StateInFlight)
+}
+
+func TestQueuesStoredChildrenMissingFromFrontier(t *testing.T) {
+	r, g, f := newTestHandler(t, 0)
+	seed := model.TitleQuery{Wiki: "en", Title: "A", Seed: "A"}
+	// B was stored by a page whose frontier push never happened
+	if _, _, err := g.UpsertTitles([]model.TitleQuery{seed.Child("B")}); err != nil {
+		t.Fatal(err)
+	}
+
+	r.rawdataHandler(page(lease(t, f, seed), "B"))
+	wantState(t, f, "B", frontier.StatePending)
+}
+
+// crawl leases the next title and stores it with links, as the fetcher and
+// the handler would; it fails when the frontier hands out another title.
+func crawl(t *testing.T, r *RawDataHandler, f frontier.Frontier, want string, links ...string) model.TitleQuery {
+	t.Helper()
+	items, err := f.Lease(1)
+	if err != nil || len(items) != 1 || items[0].Title != want {
+		t.Fatalf("Lease = %v, %v; want %s", items, err, want)
+	}
+	r.rawdataHandler(page(items[0], links...))
+	return items[0]
+}
+
+// wantWithin checks TitlesWithinHops of every seed in want.
+func wantWithin(t *testing.T, g graphstore.Graph, hops int, want map[string][]string) {
+	t.Helper()
+	for seed, names := range want {
+		got, err := g.TitlesWithinHops("en", seed, hops)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, names) {
+			t.Errorf("within %d of %s = %v, want %v", hops, seed, got, names)
+		}
+	}
+}
+
+func TestNewSeedIsCarriedOverStoredLinks(t *testing.T) {
+	r, g, f := newTestHandler(t, 0)
+	f.Push(model.TitleQuery{Wiki: "en", Title: "S", Seed: "S"})
+	crawl(t, r, f, "S", "X")
+	crawl(t, r, f, "X", "Y")
+	crawl(t, r, f, "Y", "Z")
+	crawl(t, r, f, "Z")
+
+	// A second seed reaches Y in one hop: Y and Z are not fetched again
+	f.Push(model.TitleQuery{Wiki: "en", Title: "T", Seed: "T"})
+	crawl(t, r, f, "T", "Y")
+	for _, title := range []string{"Y", "Z"} {
+		wantState(t, f, title, frontier.StateDone)
+	}
+	if items, _ := f.Lease(1); len(items) != 0 {
+		t.Errorf("leased %v after T, want nothing left", items)
+	}
+	wantWithin(t, g, 5, map[string][]string{
+		"S": {"S", "X", "Y", "Z"},
+		"T": {"T", "Y", "Z"},
+	})
+}
+
+func TestCloserSeedCrawlsLinksPastTheOldMaxDepth(t *testing.T) {
+	r, g, f := newTestHandler(t, 2)
+	f.Push(model.TitleQuery{Wiki: "en", Title: "S", Seed: "S"})
+	crawl(t, r, f, "S", "X")
+	crawl(t, r, f, "X", "Y")
+	crawl(t, r, f, "Y", "Z") // depth 2: Z is stored but not crawled
+	if _, ok := f.State("en", "Z"); ok {
+		t.Fatal("Z queued past max depth")
+	}
+
+	// T reaches Y in one hop: Y stays done, Z is now within 2 hops of T and crawled
+	f.Push(model.TitleQuery{Wiki: "en", Title: "T", Seed: "T"})
+	crawl(t, r, f, "T", "Y")
+	wantState(t, f, "Y", frontier.StateDone)
+	if z := crawl(t, r, f, "Z", "W"); z.Depth != 2 || z.Seed != "T" {
+		t.Errorf("Z queued at depth %d from %q, want 2 from T", z.Depth, z.Seed)
+	}
+	if items, _ := f.Lease(1); len(items) != 0 {
+		t.Errorf("leased %v, want W stored but not crawled", items)
+	}
+	wantWithin(t, g, 3, map[string][]string{
+		"S": {"S", "X", "Y", "Z"},
+		"T": {"T", "Y", "Z", "W"},
+	})
+}
+
+// links returns the names of the titles linked from (en, title), sorted.
+func links(t *testing.T, g graphstore.Graph, title string) []string {
+	t.Helper()
+	id, err := g.TitleIDByName("en", title)
+	if err != nil {
+		t.Fatal(err)
+	}
+	neighbors, err := g.Neighbors([]string{id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, err := g.TitleNames(neighbors[id])
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := []string{}
+	for _, name := range names {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestStoresLinksAsEdges(t *testing.T) {
+	r, g, f := newTestHandler(t, 0)
+	data := page(lease(t, f, model.TitleQuery{Wiki: "en", Title: "A", Seed: "A"}), "B", "C", "B")
+	p := data.LinksRes.Query.Pages["1"]
+	p.Links = append(p.Links, model.WikiLink{Ns: 14, Title: "Category:Letters"})
+	data.LinksRes.Query.Pages["1"] = p
+
+	r.rawdataHandler(data)
+
+	if got := links(t, g, "A"); !reflect.DeepEqual(got, []string{"B", "C"}) {
+		t.Errorf("links of A = %v, want [B C]", got)
+	}
+	counts, err := g.Counts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts.Titles != 3 || counts.Pairs != 2 {
+		t.Errorf("Counts = %+v, want 3 titles and 2 pairs", counts)
+	}
+	if _, ok := f.State("en", "Category:Letters"); ok {
+		t.Error("link outside the main namespace queued")
+	}
+}
+
+func TestRedirectIsMergedIntoItsTarget(t *testing.T) {
+	r, g, f := newTestHandler(t, 0)
+	r.rawdataHandler(page(lease(t, f, model.TitleQuery{Wiki: "en", Title: "A", Seed: "A"}), "Bee"))
+
+	// "Bee" redirects to "B": the node stored for the link becomes B
+	items, _ := f.Lease(1)
+	if len(items) != 1 || items[0].Title != "Bee" {
+		t.Fatalf("Lease = %v, want Bee", items)
+	}
+	data := page(items[0], "C")
+	p := data.LinksRes.Query.Pages["1"]
+	p.Title = "B"
+	data.LinksRes.Query.Pages["1"] = p
+	data.LinksRes.Query.Redirects = []model.Redirect{{From: "Bee", To: "B"}}
+	r.rawdataHandler(data)
+	wantState(t, f, "Bee", frontier.StateDone)
+
+	if _, err := g.TitleIDByName("en", "Bee"); !errors.Is(err, graphstore.ErrNotFound) {
+		t.Errorf("'Bee' is still a title: %v", err)
+	}
+	ids, _ := g.ResolveAliases("en", []string{"Bee"})
+	if ids["Bee"] != model.TitleID("en", "B") {
+		t.Errorf("alias Bee -> %q, want the ID of B", ids["Bee"])
+	}
+	if got := links(t, g, "A"); !reflect.DeepEqual(got, []string{"B"}) {
+		t.Errorf("links of A = %v, want [B]", got)
+	}
+	if got := links(t, g, "B"); !reflect.DeepEqual(got, []string{"C"}) {
+		t.Errorf("links of B = %v, want [C]", got)
+	}
+}
+
+func TestLinksPastMaxDepthAreStoredNotCrawled(t *testing.T) {
+	r, g, f := newTestHandler(t, 1)
+	f.Push(model.TitleQuery{Wiki: "en", Title: "S", Seed: "S"})
+	crawl(t, r, f, "S", "A")
+	crawl(t, r, f, "A", "B") // A is at max depth 1
+
+	if got := links(t, g, "A"); !reflect.DeepEqual(got, []string{"B"}) {
+		t.Errorf("links of A = %v, want [B]", got)
+	}
+	if _, ok := f.State("en", "B"); ok {
+		t.Error("B queued past max depth")
+	}
+	if seeds, _ := g.TitleSeeds(model.TitleID("en", "B")); !reflect.DeepEqual(seeds, map[string]int{"S": 2}) {
+		t.Errorf("seeds of B = %v, want S at 2", seeds)
+	}
+	if items, _ := f.Lease(1); len(items) != 0 {
+		t.Errorf("frontier still hands out %v", items)
+	}
+}
+
+func TestMissingAndInvalidTitlesAreMarked(t *testing.T) {
+	r, g, f := newTestHandler(t, 0)
+	for _, c := range []struct {
+		title string
+		page  model.WikiPage
+	}{
+		{"Nowhere", model.WikiPage{Title: "Nowhere", Missing: true}},
+		{"Bad[title", model.WikiPage{Title: "Bad[title", Invalid: true, InvalidReason: "contains invalid characters"}},
+	} {
+		q := lease(t, f, model.TitleQuery{Wiki: "en", Title: c.title, Seed: c.title})
+		data := model.RawDataWiki{TitleQ: q, Err: api.PageError(c.page)}
+		data.LinksRes.Query.Pages = map[string]model.WikiPage{"-1": c.page}
+		r.rawdataHandler(data)
+		wantState(t, f, c.title, frontier.StateDone)
+	}
+
+	counts, err := g.Counts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts.ByStatus[graphstore.StatusMissing] != 1 || counts.ByStatus[graphstore.StatusInvalid] != 1 {
+		t.Errorf("titles by status = %v, want one missing and one invalid", counts.ByStatus)
+	}
+}
+
+func TestStopClosesTheSpillFileAfterTheLastDispatch(t *testing.T) {
+	rawDataQ := make(chan model.RawDataWiki, 1000)
+	for i := 0; i < cap(rawDataQ); i++ {
+		rawDataQ <- page(model.TitleQuery{Wiki: "en", Title: fmt.Sprint("P", i), Seed: "P0"}, "A")
+	}
+	// No worker ever takes a page: every one the loop reads goes to the spill file
+	r, err := NewRawDataHandler(graphstore.NewMemoryGraphStore(), rawDataQ, frontier.NewMemoryFrontier(time.Minute),
+		0, 1, 0, 5*time.Millisecond, filepath.Join(t.TempDir(), "spill"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Start(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if err := r.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond) // a dispatch still running would fail by now
+	if s := r.Stats(); s.Dropped != 0 || s.Spilled == 0 {
+		t.Errorf("stats %+v: want pages spilled and none dropped", s)
+	}
+}
+
+func TestTitleWithoutWikiIsStoredUnderTheFetchedOne(t *testing.T) {
+	r, g, f := newTestHandler(t, 0)
+	leased := lease(t, f, model.TitleQuery{Title: "A", Seed: "A"})
+	data := page(leased, "B")
+	data.Wiki = "en"
+
+	r.rawdataHandler(data)
+
+	if state, _ := f.State("", "A"); state != frontier.StateDone {
+		t.Errorf("leased A: state %q, want done", state)
+	}
+	wantState(t, f, "B", frontier.StatePending)
+	id, err := g.TitleIDByName("en", "A")
+	if err != nil || id != model.TitleID("en", "A") {
+		t.Errorf("A stored as %q, %v; want %q", id, err, model.TitleID("en", "A"))
+	}
+}
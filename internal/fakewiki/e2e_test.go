@@ -0,0 +1,162 @@
+package fakewiki
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+	"wikicrawler/internal/core/apiclient"
+	"wikicrawler/internal/core/rawdatahandler"
+	"wikicrawler/internal/infra"
+	"wikicrawler/internal/infra/frontier"
+	"wikicrawler/internal/infra/graphstore"
+	"wikicrawler/internal/model"
+	"wikicrawler/internal/utils/ratelimit"
+	"wikicrawler/internal/utils/retry"
+)
+
+// wantEdges is page -> the pages it links to, redirects resolved, for every
+// page reachable from seed.
+func wantEdges(g *Graph, seed string) map[string][]string {
+	canonical := func(title string) string {
+		if to, ok := g.Redirects[title]; ok {
+			return to
+		}
+		return title
+	}
+	edges := make(map[string][]string)
+	queue := []string{seed}
+	for len(queue) > 0 {
+		page := queue[0]
+		queue = queue[1:]
+		if _, done := edges[page]; done {
+			continue
+		}
+		targets := map[string]bool{}
+		for _, l := range g.Pages[page] {
+			targets[canonical(l)] = true
+		}
+		edges[page] = []string{}
+		for t := range targets {
+			edges[page] = append(edges[page], t)
+			queue = append(queue, t)
+		}
+		sort.Strings(edges[page])
+	}
+	return edges
+}
+
+// crawl runs the fetch workers and the handler on a memory store against
+// wiki until the frontier has nothing left, and returns the stored graph.
+func crawl(t *testing.T, wiki *Server, seed string) (*graphstore.MemoryGraphStore, *frontier.MemoryFrontier, string) {
+	t.Helper()
+	srv := httptest.NewServer(wiki)
+	t.Cleanup(srv.Close)
+
+	g := graphstore.NewMemoryGraphStore()
+	f := frontier.NewMemoryFrontier(time.Minute)
+	store := &infra.WikiStore{Frontier: f, Graph: g, RawDataQ: make(chan model.RawDataWiki, 100)}
+	client := apiclient.NewAPIClient(store, 5*time.Second, time.Minute, 10, 10, time.Millisecond, srv.URL, 50, 2,
+		ratelimit.NewTokenBucket(0, 1),
+		retry.Exponential{Base: time.Millisecond, Max: 5 * time.Millisecond, MaxAttempts: 30},
+		5, 0)
+	// One handler worker: pages are stored one after the other, like one Postgres row lock would
+	handler, err := rawdatahandler.NewRawDataHandler(g, store.RawDataQ, f, 1, 100, 0, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Push(model.TitleQuery{Wiki: srv.URL, Title: seed, Seed: seed}); err != nil {
+		t.Fatal(err)
+	}
+	if err := handler.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Start(); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		counts, _ := f.Counts()
+		if counts[frontier.StatePending] == 0 && counts[frontier.StateInFlight] == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("crawl did not finish: frontier %v", counts)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	client.Stop()
+	handler.Stop()
+
+	if counts, _ := f.Counts(); counts[frontier.StateFailed] != 0 {
+		t.Fatalf("frontier %v: titles failed", counts)
+	}
+	return g, f, srv.URL
+}
+
+func TestCrawlStoresTheGeneratedGraph(t *testing.T) {
+	// 30 links per page: a batch of 50 titles takes several plcontinue pages
+	graph := Generate(150, 30, 7)
+	want := wantEdges(graph, PageTitle(1))
+
+	var clean int64 // requests a crawl takes when nothing goes wrong
+	for _, c := range []struct {
+		name   string
+		faults Faults
+	}{
+		{"no faults", Faults{}},
+		{"maxlag", Faults{Maxlag: 0.3, RetryAfter: time.Millisecond}},
+		{"429", Faults{RateLimited: 0.3, RetryAfter: time.Millisecond}},
+		{"truncated responses", Faults{Malformed: 0.3}}, // plcontinue pages included
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			wiki := NewServer(graph, c.faults, 1)
+			g, _, wikiName := crawl(t, wiki, PageTitle(1))
+
+			got := make(map[string][]string)
+			err := g.ForEachEdge(func(w, src, dst string) error {
+				if w != wikiName {
+					t.Errorf("edge %s -> %s stored for wiki %q", src, dst, w)
+				}
+				got[src] = append(got[src], dst)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for page, targets := range got {
+				sort.Strings(targets)
+				got[page] = targets
+			}
+			for page, targets := range want {
+				if len(targets) == 0 {
+					got[page] = targets // pages without links have no edges to report
+				}
+			}
+			if !reflect.DeepEqual(got, want) {
+				for page := range want {
+					if !reflect.DeepEqual(got[page], want[page]) {
+						t.Errorf("%s: stored %v, generated %v", page, got[page], want[page])
+					}
+				}
+				for page := range got {
+					if _, ok := want[page]; !ok {
+						t.Errorf("%s: stored but not reachable (a redirect left unmerged?)", page)
+					}
+				}
+			}
+
+			counts, _ := g.Counts()
+			if counts.Titles != int64(len(want)) {
+				t.Errorf("%d titles stored, %d reachable", counts.Titles, len(want))
+			}
+			if c.faults == (Faults{}) {
+				clean = wiki.Requests()
+			} else if wiki.Requests() <= clean {
+				t.Errorf("%d requests served, as many as without faults: none was retried", wiki.Requests())
+			}
+		})
+	}
+}
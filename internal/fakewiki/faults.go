@@ -0,0 +1,68 @@
+package fakewiki
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Faults are the odds (0..1) that a request is answered badly instead of
+// with its links. At most one fault is injected per request.
+type Faults struct {
+	RateLimited float64       // 429 with Retry-After
+	ServerError float64       // 503
+	Maxlag      float64       // MediaWiki "maxlag" error, HTTP 200
+	Malformed   float64       // truncated JSON body
+	Slow        float64       // answer after SlowDelay
+	SlowDelay   time.Duration // default 2s
+	RetryAfter  time.Duration // sent with 429 and maxlag, default 1s
+}
+
+type fault int
+
+const (
+	noFault fault = iota
+	faultRateLimited
+	faultServerError
+	faultMaxlag
+	faultMalformed
+	faultSlow
+)
+
+type faultPicker struct {
+	mu sync.Mutex
+	r  *rand.Rand
+	f  Faults
+}
+
+func newFaultPicker(f Faults, seed uint64) *faultPicker {
+	if f.SlowDelay <= 0 {
+		f.SlowDelay = 2 * time.Second
+	}
+	if f.RetryAfter <= 0 {
+		f.RetryAfter = time.Second
+	}
+	return &faultPicker{r: rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15)), f: f}
+}
+
+func (p *faultPicker) pick() fault {
+	p.mu.Lock()
+	x := p.r.Float64()
+	p.mu.Unlock()
+	for _, c := range []struct {
+		odds  float64
+		fault fault
+	}{
+		{p.f.RateLimited, faultRateLimited},
+		{p.f.ServerError, faultServerError},
+		{p.f.Maxlag, faultMaxlag},
+		{p.f.Malformed, faultMalformed},
+		{p.f.Slow, faultSlow},
+	} {
+		if x < c.odds {
+			return c.fault
+		}
+		x -= c.odds
+	}
+	return noFault
+}
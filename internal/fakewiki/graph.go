@@ -0,0 +1,81 @@
+package fakewiki
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"sort"
+)
+
+// Graph is the link graph a fake wiki serves.
+type Graph struct {
+	Pages     map[string][]string `json:"pages"`     // title -> linked titles, in order
+	Redirects map[string]string   `json:"redirects"` // redirect title -> target title
+}
+
+// LoadGraph reads a fixture:
+//
+//	{"pages": {"A": ["B", "C"], "B": ["A"]}, "redirects": {"Bee": "B"}}
+//
+// Linked titles without a page of their own are served as missing pages.
+func LoadGraph(path string) (*Graph, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var g Graph
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("[FakeWiki] invalid graph %s: %w", path, err)
+	}
+	if g.Pages == nil {
+		g.Pages = map[string][]string{}
+	}
+	if g.Redirects == nil {
+		g.Redirects = map[string]string{}
+	}
+	return &g, nil
+}
+
+// Generate builds a random graph of n pages named "Page 1".."Page n" with
+// degree links each, plus a "Redirect to Page i" alias for every tenth page.
+// The same seed always gives the same graph.
+func Generate(n, degree int, seed uint64) *Graph {
+	r := rand.New(rand.NewPCG(seed, seed))
+	g := &Graph{Pages: make(map[string][]string, n), Redirects: map[string]string{}}
+	for i := 1; i <= n; i++ {
+		links := make([]string, 0, degree)
+		for j := 0; j < degree; j++ {
+			target := r.IntN(n) + 1
+			if target%10 == 0 && r.IntN(2) == 0 {
+				links = append(links, fmt.Sprintf("Redirect to Page %d", target))
+			} else {
+				links = append(links, fmt.Sprintf("Page %d", target))
+			}
+		}
+		g.Pages[PageTitle(i)] = links
+		if i%10 == 0 {
+			g.Redirects[fmt.Sprintf("Redirect to Page %d", i)] = PageTitle(i)
+		}
+	}
+	return g
+}
+
+// PageTitle is the title of the i-th generated page.
+func PageTitle(i int) string {
+	return fmt.Sprintf("Page %d", i)
+}
+
+// index assigns page IDs in title order, so they are stable across restarts.
+func (g *Graph) index() (ids map[string]int, titles []string) {
+	titles = make([]string, 0, len(g.Pages))
+	for t := range g.Pages {
+		titles = append(titles, t)
+	}
+	sort.Strings(titles)
+	ids = make(map[string]int, len(titles))
+	for i, t := range titles {
+		ids[t] = i + 1
+	}
+	return ids, titles
+}
@@ -0,0 +1,249 @@
+package fakewiki
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	maxTitles = 50  // titles per request, like MediaWiki for normal users
+	maxLinks  = 500 // links per response for pllimit=max
+)
+
+// Server answers MediaWiki action=query&prop=links requests (formatversion 1)
+// from a Graph, on any path, so a crawler can point crawler.wiki at
+// http://host:port/w/api.php. It normalizes titles, follows redirects with
+// redirects=1, reports missing and invalid titles and paginates with plcontinue.
+type Server struct {
+	graph    *Graph
+	ids      map[string]int
+	titles   []string // by page ID - 1
+	faults   *faultPicker
+	requests atomic.Int64
+}
+
+func NewServer(g *Graph, faults Faults, seed uint64) *Server {
+	s := &Server{graph: g, faults: newFaultPicker(faults, seed)}
+	s.ids, s.titles = g.index()
+	return s
+}
+
+// Requests returns how many requests were served so far.
+func (s *Server) Requests() int64 {
+	return s.requests.Load()
+}
+
+type link struct {
+	Ns    int    `json:"ns"`
+	Title string `json:"title"`
+}
+
+type page struct {
+	Pageid        int     `json:"pageid,omitempty"`
+	Ns            int     `json:"ns"`
+	Title         string  `json:"title"`
+	Links         []link  `json:"links,omitempty"`
+	Missing       *string `json:"missing,omitempty"`
+	Invalid       *string `json:"invalid,omitempty"`
+	InvalidReason string  `json:"invalidreason,omitempty"`
+}
+
+type fromTo struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type response struct {
+	Batchcomplete *string           `json:"batchcomplete,omitempty"`
+	Continue      map[string]string `json:"continue,omitempty"`
+	Query         struct {
+		Normalized []fromTo        `json:"normalized,omitempty"`
+		Redirects  []fromTo        `json:"redirects,omitempty"`
+		Pages      map[string]page `json:"pages"`
+	} `json:"query"`
+	Limits map[string]int `json:"limits,omitempty"`
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.requests.Add(1)
+	q := r.URL.Query()
+
+	switch s.faults.pick() {
+	case faultRateLimited:
+		w.Header().Set("Retry-After", strconv.Itoa(int(s.faults.f.RetryAfter.Seconds())))
+		http.Error(w, "Too many requests", http.StatusTooManyRequests)
+		return
+	case faultServerError:
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		return
+	case faultMaxlag:
+		if q.Get("maxlag") != "" {
+			w.Header().Set("Retry-After", strconv.Itoa(int(s.faults.f.RetryAfter.Seconds())))
+			writeError(w, "maxlag", "Waiting for a database server: 6 seconds lagged.")
+			return
+		}
+	case faultMalformed:
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprint(w, `{"query":{"pages":{"1":{"pageid":1,"title":`)
+		return
+	case faultSlow:
+		time.Sleep(s.faults.f.SlowDelay)
+	}
+
+	if q.Get("action") != "query" || q.Get("prop") != "links" {
+		writeError(w, "badvalue", "The fake wiki only serves action=query&prop=links.")
+		return
+	}
+	titles := strings.Split(q.Get("titles"), "|")
+	if q.Get("titles") == "" {
+		writeError(w, "missingparam", "The titles parameter must be set.")
+		return
+	}
+	if len(titles) > maxTitles {
+		writeError(w, "toomanyvalues", fmt.Sprintf("Too many values supplied for parameter titles. The limit is %d.", maxTitles))
+		return
+	}
+	limit := maxLinks
+	if v := q.Get("pllimit"); v != "" && v != "max" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, "badinteger", fmt.Sprintf("Invalid value %q for integer parameter pllimit.", v))
+			return
+		}
+		limit = min(n, maxLinks)
+	}
+
+	res, ok := s.query(titles, q.Get("redirects") != "", q.Get("plcontinue"), limit)
+	if !ok {
+		writeError(w, "badcontinue", "Invalid continue param. You should pass the original value returned by the previous query.")
+		return
+	}
+	writeJSON(w, res)
+}
+
+func (s *Server) query(titles []string, redirects bool, plcontinue string, limit int) (*response, bool) {
+	res := &response{Limits: map[string]int{"links": maxLinks}}
+	res.Query.Pages = map[string]page{}
+	empty := ""
+
+	var found []int // page IDs of existing pages, for the links
+	seen := map[string]bool{}
+	negative := 0
+	for _, t := range titles {
+		n := normalize(t)
+		if n != t && !seen["n:"+t] {
+			seen["n:"+t] = true
+			res.Query.Normalized = append(res.Query.Normalized, fromTo{From: t, To: n})
+		}
+		if reason := invalidReason(n); reason != "" {
+			negative--
+			res.Query.Pages[strconv.Itoa(negative)] = page{Title: t, Invalid: &empty, InvalidReason: reason}
+			continue
+		}
+		for hops := 0; redirects && hops < 10; hops++ {
+			to, ok := s.graph.Redirects[n]
+			if !ok {
+				break
+			}
+			if !seen["r:"+n] {
+				seen["r:"+n] = true
+				res.Query.Redirects = append(res.Query.Redirects, fromTo{From: n, To: to})
+			}
+			n = to
+		}
+		if seen["p:"+n] {
+			continue
+		}
+		seen["p:"+n] = true
+
+		id, ok := s.ids[n]
+		if !ok {
+			negative--
+			res.Query.Pages[strconv.Itoa(negative)] = page{Title: n, Missing: &empty}
+			continue
+		}
+		res.Query.Pages[strconv.Itoa(id)] = page{Pageid: id, Title: n}
+		found = append(found, id)
+	}
+	sort.Ints(found)
+
+	// plcontinue is "<pageid>|0|<index of the next link of that page>"
+	startID, startAt := 0, 0
+	if plcontinue != "" {
+		parts := strings.Split(plcontinue, "|")
+		if len(parts) != 3 {
+			return nil, false
+		}
+		var err1, err2 error
+		startID, err1 = strconv.Atoi(parts[0])
+		startAt, err2 = strconv.Atoi(parts[2])
+		if err1 != nil || err2 != nil || startAt < 0 {
+			return nil, false
+		}
+	}
+
+	for _, id := range found {
+		if id < startID {
+			continue
+		}
+		title := s.titles[id-1]
+		links := s.graph.Pages[title]
+		from := 0
+		if id == startID {
+			from = min(startAt, len(links))
+		}
+		p := res.Query.Pages[strconv.Itoa(id)]
+		for i := from; i < len(links); i++ {
+			if limit == 0 {
+				res.Continue = map[string]string{"plcontinue": fmt.Sprintf("%d|0|%d", id, i), "continue": "||"}
+				res.Query.Pages[strconv.Itoa(id)] = p
+				return res, true
+			}
+			p.Links = append(p.Links, link{Ns: 0, Title: links[i]})
+			limit--
+		}
+		res.Query.Pages[strconv.Itoa(id)] = p
+	}
+	res.Batchcomplete = &empty
+	return res, true
+}
+
+// normalize turns "donald_trump" into "Donald trump" like MediaWiki does for
+// the main namespace.
+func normalize(t string) string {
+	t = strings.TrimSpace(strings.ReplaceAll(t, "_", " "))
+	r, size := utf8.DecodeRuneInString(t)
+	if r == utf8.RuneError {
+		return t
+	}
+	return string(unicode.ToUpper(r)) + t[size:]
+}
+
+func invalidReason(t string) string {
+	if t == "" {
+		return "The requested page title is empty or contains only the name of a namespace."
+	}
+	if i := strings.IndexAny(t, "#<>[]{}|"); i >= 0 {
+		return fmt.Sprintf("The requested page title contains invalid characters: \"%c\".", t[i])
+	}
+	return ""
+}
+
+func writeError(w http.ResponseWriter, code, info string) {
+	writeJSON(w, map[string]any{"error": map[string]string{"code": code, "info": info}})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Printf("[FakeWiki] failed to write response: %v\n", err)
+	}
+}
@@ -0,0 +1,57 @@
+package frontier
+
+import (
+	"wikicrawler/internal/infra/graphstore"
+	"wikicrawler/internal/model"
+)
+
+// State of a title in the crawl frontier.
+type State string
+
+const (
+	StatePending  State = "pending"   // discovered, waiting to be fetched
+	StateInFlight State = "in_flight" // leased by a fetcher until its lease expires
+	StateDone     State = "done"      // every page of links was fetched
+	StateFailed   State = "failed"    // gave up after the retry policy was exhausted
+)
+
+// Frontier is the durable queue of titles to crawl. Every title is accepted
+// once; a leased title that is neither Done nor Released before its lease
+// expires (e.g. the fetcher or the handler crashed) becomes leasable again.
+// A title stays in flight until the handler stored its links, so Done comes
+// from the handler and not from the fetcher.
+type Frontier interface {
+	// Push adds titles that were never seen before and returns how many were new.
+	Push(items ...model.TitleQuery) (int, error)
+	// Requeue queues titles that were reached closer to a seed than before (see
+	// graphstore.Graph.UpsertTitles): a pending one moves up to the lower depth
+	// and its seed, an unknown one is added. Titles in flight, done or failed
+	// are left alone; the handler carries the new depth over the links stored
+	// for them instead of fetching them again. It returns how many titles were
+	// added or moved up.
+	Requeue(items ...model.TitleQuery) (int, error)
+	// Lease hands out up to n titles, lower depth first, and marks them in-flight.
+	Lease(n int) ([]model.TitleQuery, error)
+	// Done marks a leased title as fully fetched and stored. Like Release and
+	// Fail, it does nothing to a title that is not in flight (e.g. a late
+	// duplicate of a page whose lease expired and that was stored already).
+	Done(item model.TitleQuery) error
+	// Release gives a leased title back so it is fetched again later.
+	Release(item model.TitleQuery) error
+	// Fail marks a leased title as failed for good and records why.
+	Fail(item model.TitleQuery, cause error) error
+	// Renew extends the lease of titles still in flight by a full lease timeout.
+	Renew(items ...model.TitleQuery) error
+	// Counts returns the number of titles per state.
+	Counts() (map[State]int64, error)
+}
+
+// TxFrontier is a Frontier that can join the transaction of a graph store, so
+// the links of a page, the titles they queue and the Done of the page are
+// committed together.
+type TxFrontier interface {
+	Frontier
+	// InTx returns the frontier inside the transaction of tx (a Graph passed to
+	// GraphStore.WithTx), or false when it cannot join that transaction.
+	InTx(tx graphstore.Graph) (Frontier, bool)
+}
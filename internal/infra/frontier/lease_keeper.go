@@ -0,0 +1,142 @@
+package frontier
+
+import (
+	"log"
+	"sync"
+	"time"
+	"wikicrawler/internal/infra/graphstore"
+	"wikicrawler/internal/model"
+	"wikicrawler/internal/utils/processor"
+)
+
+// LeaseKeeper is a Frontier that renews the leases of the titles leased
+// through it until they are Done, Released or Failed. A batch that waits out
+// retries, or a page that waits for the handler (or in the spill file), is
+// then not handed out again while it is still being worked on; a crashed
+// process stops renewing, and its leases expire as before.
+type LeaseKeeper struct {
+	processor.BaseProcessor
+	inner Frontier
+	every time.Duration
+
+	mu   sync.Mutex
+	held map[string]model.TitleQuery // leased through the keeper, by Key
+}
+
+// NewLeaseKeeper renews every interval, which should be well below the lease
+// timeout of inner (e.g. a third of it).
+func NewLeaseKeeper(inner Frontier, every time.Duration) *LeaseKeeper {
+	k := &LeaseKeeper{
+		inner: inner,
+		every: every,
+		held:  make(map[string]model.TitleQuery),
+	}
+	k.Init(k)
+	return k
+}
+
+func (k *LeaseKeeper) RunningTask() {
+	time.Sleep(k.every)
+	k.renew()
+}
+
+func (k *LeaseKeeper) renew() {
+	k.mu.Lock()
+	items := make([]model.TitleQuery, 0, len(k.held))
+	for _, it := range k.held {
+		items = append(items, it)
+	}
+	k.mu.Unlock()
+
+	if err := k.inner.Renew(items...); err != nil {
+		log.Printf("[LeaseKeeper] %v", err)
+	}
+}
+
+// Held returns how many leased titles are being renewed.
+func (k *LeaseKeeper) Held() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.held)
+}
+
+func (k *LeaseKeeper) forget(item model.TitleQuery) {
+	k.mu.Lock()
+	delete(k.held, item.Key())
+	k.mu.Unlock()
+}
+
+func (k *LeaseKeeper) Push(items ...model.TitleQuery) (int, error) {
+	return k.inner.Push(items...)
+}
+
+func (k *LeaseKeeper) Requeue(items ...model.TitleQuery) (int, error) {
+	return k.inner.Requeue(items...)
+}
+
+func (k *LeaseKeeper) Lease(n int) ([]model.TitleQuery, error) {
+	items, err := k.inner.Lease(n)
+	k.mu.Lock()
+	for _, it := range items {
+		k.held[it.Key()] = it
+	}
+	k.mu.Unlock()
+	return items, err
+}
+
+func (k *LeaseKeeper) Done(item model.TitleQuery) error {
+	k.forget(item)
+	return k.inner.Done(item)
+}
+
+func (k *LeaseKeeper) Release(item model.TitleQuery) error {
+	k.forget(item)
+	return k.inner.Release(item)
+}
+
+func (k *LeaseKeeper) Fail(item model.TitleQuery, cause error) error {
+	k.forget(item)
+	return k.inner.Fail(item, cause)
+}
+
+func (k *LeaseKeeper) Renew(items ...model.TitleQuery) error {
+	return k.inner.Renew(items...)
+}
+
+func (k *LeaseKeeper) Counts() (map[State]int64, error) {
+	return k.inner.Counts()
+}
+
+// InTx joins tx when the wrapped frontier can; Done in tx stops the renewals.
+func (k *LeaseKeeper) InTx(tx graphstore.Graph) (Frontier, bool) {
+	tf, ok := k.inner.(TxFrontier)
+	if !ok {
+		return nil, false
+	}
+	inner, ok := tf.InTx(tx)
+	if !ok {
+		return nil, false
+	}
+	return &keptTx{Frontier: inner, keeper: k}, true
+}
+
+// keptTx is the wrapped frontier inside one transaction.
+type keptTx struct {
+	Frontier
+	keeper *LeaseKeeper
+}
+
+func (t *keptTx) Done(item model.TitleQuery) error {
+	t.keeper.forget(item)
+	return t.Frontier.Done(item)
+}
+
+func (t *keptTx) Release(item model.TitleQuery) error {
+	t.keeper.forget(item)
+	return t.Frontier.Release(item)
+}
+
+func (t *keptTx) Fail(item model.TitleQuery, cause error) error {
+	t.keeper.forget(item)
+	return t.Frontier.Fail(item, cause)
+}
@@ -0,0 +1,57 @@
+package frontier
+
+import (
+	"testing"
+	"time"
+	"wikicrawler/internal/model"
+)
+
+func TestLeaseKeeperRenewsUntilDone(t *testing.T) {
+	now := time.Unix(0, 0)
+	inner := NewMemoryFrontier(time.Minute)
+	inner.now = func() time.Time { return now }
+	k := NewLeaseKeeper(inner, 20*time.Second)
+	k.Push(model.TitleQuery{Wiki: "en", Title: "A"}, model.TitleQuery{Wiki: "en", Title: "B"})
+
+	items, _ := k.Lease(2)
+	if len(items) != 2 || k.Held() != 2 {
+		t.Fatalf("Lease = %v, held %d", items, k.Held())
+	}
+
+	// Well past the first lease, but renewed on the way
+	for i := 0; i < 6; i++ {
+		now = now.Add(20 * time.Second)
+		k.renew()
+	}
+	if again, _ := inner.Lease(2); len(again) != 0 {
+		t.Fatalf("renewed titles leased again: %v", again)
+	}
+
+	// Done stops the renewals of A; B is released and leasable at once
+	k.Done(items[0])
+	k.Release(items[1])
+	if k.Held() != 0 {
+		t.Fatalf("held %d after Done and Release", k.Held())
+	}
+	if s, _ := inner.State("en", items[0].Title); s != StateDone {
+		t.Errorf("%s: state %q, want done", items[0].Title, s)
+	}
+	if again, _ := inner.Lease(2); len(again) != 1 || again[0].Title != items[1].Title {
+		t.Errorf("Lease after Release = %v", again)
+	}
+}
+
+func TestLeaseKeeperStopsWithProcess(t *testing.T) {
+	now := time.Unix(0, 0)
+	inner := NewMemoryFrontier(time.Minute)
+	inner.now = func() time.Time { return now }
+	k := NewLeaseKeeper(inner, 20*time.Second)
+	k.Push(model.TitleQuery{Wiki: "en", Title: "A"})
+	k.Lease(1)
+
+	// No renewals (the process died): the lease expires as before
+	now = now.Add(2 * time.Minute)
+	if again, _ := inner.Lease(1); len(again) != 1 {
+		t.Fatalf("expired lease not handed out again: %v", again)
+	}
+}
@@ -0,0 +1,155 @@
+package frontier
+
+import (
+	"sort"
+	"sync"
+	"time"
+	"wikicrawler/internal/model"
+)
+
+// MemoryFrontier keeps the frontier in process memory, with the same states,
+// order and leases as the durable backends. It is lost on restart, so it is
+// meant for tests and one-off crawls (e.g. against fakewiki).
+type MemoryFrontier struct {
+	mu    sync.Mutex
+	lease time.Duration
+	seq   int64
+	items map[string]*memItem
+	now   func() time.Time // time.Now, replaced in tests
+}
+
+type memItem struct {
+	q          model.TitleQuery
+	state      State
+	seq        int64 // FIFO order within a depth
+	leaseUntil time.Time
+	lastError  string
+}
+
+func NewMemoryFrontier(lease time.Duration) *MemoryFrontier {
+	return &MemoryFrontier{
+		lease: lease,
+		items: make(map[string]*memItem),
+		now:   time.Now,
+	}
+}
+
+func (f *MemoryFrontier) Push(items ...model.TitleQuery) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	added := 0
+	for _, it := range items {
+		if _, ok := f.items[it.Key()]; ok {
+			continue
+		}
+		f.seq++
+		f.items[it.Key()] = &memItem{q: it, state: StatePending, seq: f.seq}
+		added++
+	}
+	return added, nil
+}
+
+func (f *MemoryFrontier) Requeue(items ...model.TitleQuery) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, it := range items {
+		old, ok := f.items[it.Key()]
+		if ok && (old.state != StatePending || it.Depth >= old.q.Depth) {
+			continue
+		}
+		f.seq++
+		if !ok {
+			f.items[it.Key()] = &memItem{q: it, state: StatePending, seq: f.seq}
+		} else {
+			old.q, old.seq = it, f.seq
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (f *MemoryFrontier) Lease(n int) ([]model.TitleQuery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	var ready []*memItem
+	for _, it := range f.items {
+		if it.state == StatePending || it.state == StateInFlight && it.leaseUntil.Before(now) {
+			ready = append(ready, it)
+		}
+	}
+	sort.Slice(ready, func(i, j int) bool {
+		if ready[i].q.Depth != ready[j].q.Depth {
+			return ready[i].q.Depth < ready[j].q.Depth
+		}
+		return ready[i].seq < ready[j].seq
+	})
+
+	var out []model.TitleQuery
+	for _, it := range ready[:min(n, len(ready))] {
+		it.state, it.leaseUntil = StateInFlight, now.Add(f.lease)
+		out = append(out, it.q)
+	}
+	return out, nil
+}
+
+func (f *MemoryFrontier) Done(item model.TitleQuery) error {
+	return f.setState(item, StateDone, "")
+}
+
+func (f *MemoryFrontier) Release(item model.TitleQuery) error {
+	return f.setState(item, StatePending, "")
+}
+
+func (f *MemoryFrontier) Fail(item model.TitleQuery, cause error) error {
+	return f.setState(item, StateFailed, cause.Error())
+}
+
+func (f *MemoryFrontier) Renew(items ...model.TitleQuery) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, item := range items {
+		if it, ok := f.items[item.Key()]; ok && it.state == StateInFlight {
+			it.leaseUntil = f.now().Add(f.lease)
+		}
+	}
+	return nil
+}
+
+func (f *MemoryFrontier) setState(item model.TitleQuery, state State, cause string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	it, ok := f.items[item.Key()]
+	if !ok || it.state != StateInFlight {
+		return nil
+	}
+	it.state, it.leaseUntil, it.lastError = state, time.Time{}, cause
+	if state == StatePending {
+		// Back to the end of its depth, like an expired lease
+		f.seq++
+		it.seq = f.seq
+	}
+	return nil
+}
+
+func (f *MemoryFrontier) Counts() (map[State]int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	counts := map[State]int64{StatePending: 0, StateInFlight: 0, StateDone: 0, StateFailed: 0}
+	for _, it := range f.items {
+		counts[it.state]++
+	}
+	return counts, nil
+}
+
+// State returns the state of (wiki, title) and whether the frontier knows it.
+func (f *MemoryFrontier) State(wiki, title string) (State, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	it, ok := f.items[model.TitleQuery{Wiki: wiki, Title: title}.Key()]
+	if !ok {
+		return "", false
+	}
+	return it.state, true
+}
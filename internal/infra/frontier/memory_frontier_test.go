@@ -0,0 +1,117 @@
+package frontier
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+	"wikicrawler/internal/model"
+)
+
+func titles(items []model.TitleQuery) []string {
+	var out []string
+	for _, it := range items {
+		out = append(out, it.Title)
+	}
+	return out
+}
+
+func TestMemoryFrontierLeaseOrder(t *testing.T) {
+	f := NewMemoryFrontier(time.Minute)
+	n, err := f.Push(
+		model.TitleQuery{Wiki: "en", Title: "deep", Depth: 2},
+		model.TitleQuery{Wiki: "en", Title: "first", Depth: 1},
+		model.TitleQuery{Wiki: "en", Title: "second", Depth: 1},
+		model.TitleQuery{Wiki: "en", Title: "first", Depth: 0}, // known already
+	)
+	if err != nil || n != 3 {
+		t.Fatalf("Push = %d, %v; want 3 new", n, err)
+	}
+
+	items, _ := f.Lease(2)
+	if got := titles(items); len(got) != 2 || got[0] != "first" || got[1] != "second" {
+		t.Fatalf("Lease(2) = %v, want [first second]", got)
+	}
+	items, _ = f.Lease(5)
+	if got := titles(items); len(got) != 1 || got[0] != "deep" {
+		t.Fatalf("Lease(5) = %v, want [deep]", got)
+	}
+}
+
+func TestMemoryFrontierLeaseExpiry(t *testing.T) {
+	now := time.Unix(0, 0)
+	f := NewMemoryFrontier(time.Minute)
+	f.now = func() time.Time { return now }
+	f.Push(model.TitleQuery{Wiki: "en", Title: "A"})
+
+	if items, _ := f.Lease(1); len(items) != 1 {
+		t.Fatalf("Lease = %v", items)
+	}
+	if items, _ := f.Lease(1); len(items) != 0 {
+		t.Fatalf("leased twice: %v", items)
+	}
+	now = now.Add(2 * time.Minute)
+	if items, _ := f.Lease(1); len(items) != 1 {
+		t.Fatalf("expired lease not handed out again: %v", items)
+	}
+}
+
+func TestMemoryFrontierOnlyInFlightChanges(t *testing.T) {
+	f := NewMemoryFrontier(time.Minute)
+	a := model.TitleQuery{Wiki: "en", Title: "A"}
+	f.Push(a)
+
+	// Not leased: Done, Release and Fail leave it pending
+	f.Done(a)
+	f.Fail(a, errors.New("boom"))
+	if s, _ := f.State("en", "A"); s != StatePending {
+		t.Fatalf("state %q, want pending", s)
+	}
+
+	f.Lease(1)
+	f.Done(a)
+	f.Release(a)
+	if s, _ := f.State("en", "A"); s != StateDone {
+		t.Fatalf("state %q, want done", s)
+	}
+	counts, _ := f.Counts()
+	if counts[StateDone] != 1 || counts[StatePending] != 0 {
+		t.Errorf("Counts = %v", counts)
+	}
+}
+
+func TestMemoryFrontierRequeue(t *testing.T) {
+	f := NewMemoryFrontier(time.Minute)
+	f.Push(model.TitleQuery{Wiki: "en", Title: "done", Depth: 3, Seed: "S"},
+		model.TitleQuery{Wiki: "en", Title: "failed", Depth: 3})
+	items, _ := f.Lease(2)
+	f.Done(items[0])
+	f.Fail(items[1], errors.New("boom"))
+	f.Push(model.TitleQuery{Wiki: "en", Title: "far", Depth: 3, Seed: "S"},
+		model.TitleQuery{Wiki: "en", Title: "near", Depth: 1, Seed: "S"})
+
+	n, _ := f.Requeue(
+		model.TitleQuery{Wiki: "en", Title: "done", Depth: 1, Seed: "T"},
+		model.TitleQuery{Wiki: "en", Title: "failed", Depth: 1},
+		model.TitleQuery{Wiki: "en", Title: "far", Depth: 0, Seed: "T"},
+		model.TitleQuery{Wiki: "en", Title: "near", Depth: 2, Seed: "T"},
+		model.TitleQuery{Wiki: "en", Title: "new", Depth: 2},
+	)
+	if n != 2 {
+		t.Errorf("Requeue = %d, want 2 (far moved up, new added)", n)
+	}
+	for title, want := range map[string]State{"done": StateDone, "failed": StateFailed} {
+		if s, _ := f.State("en", title); s != want {
+			t.Errorf("%s title is %q after Requeue", title, s)
+		}
+	}
+	items, _ = f.Lease(5)
+	var got []string
+	for _, it := range items {
+		got = append(got, fmt.Sprintf("%s@%d/%s", it.Title, it.Depth, it.Seed))
+	}
+	if want := []string{"far@0/T", "near@1/S", "new@2/"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Lease after Requeue = %v, want %v", got, want)
+	}
+}
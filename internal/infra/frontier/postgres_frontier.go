@@ -0,0 +1,155 @@
+package frontier
+
+import (
+	"fmt"
+	"time"
+	"wikicrawler/internal/infra/graphstore"
+	dbclient "wikicrawler/internal/infra/postgresclient"
+	"wikicrawler/internal/infra/postgresclient/tables"
+	"wikicrawler/internal/model"
+)
+
+// PostgresFrontier keeps the frontier in the frontier table. Leasing uses
+// FOR UPDATE SKIP LOCKED so several crawler processes can share it.
+type PostgresFrontier struct {
+	table *tables.FrontierTable
+	lease time.Duration
+}
+
+func NewPostgresFrontier(db *dbclient.PostgresClient, lease time.Duration) *PostgresFrontier {
+	return &PostgresFrontier{
+		table: tables.NewFrontierTable(db), // created by migrations, see WikiStore.EnsureTables
+		lease: lease,
+	}
+}
+
+// InTx joins the transaction of a PostgresGraphStore on the same database.
+func (f *PostgresFrontier) InTx(g graphstore.Graph) (Frontier, bool) {
+	pg, ok := g.(*graphstore.PostgresGraphStore)
+	if !ok {
+		return nil, false
+	}
+	ctx, tx := pg.Tx()
+	if tx == nil {
+		return nil, false
+	}
+	return &PostgresFrontier{table: f.table.Tx(ctx, tx), lease: f.lease}, true
+}
+
+func (f *PostgresFrontier) Push(items ...model.TitleQuery) (int, error) {
+	added, err := f.table.InsertMany(items)
+	if err != nil {
+		return added, fmt.Errorf("[PostgresFrontier] failed to push %d title(s): %w", len(items), err)
+	}
+	return added, nil
+}
+
+func (f *PostgresFrontier) Requeue(items ...model.TitleQuery) (int, error) {
+	n, err := f.table.Requeue(items)
+	if err != nil {
+		return n, fmt.Errorf("[PostgresFrontier] failed to requeue %d title(s): %w", len(items), err)
+	}
+	return n, nil
+}
+
+func (f *PostgresFrontier) Lease(n int) ([]model.TitleQuery, error) {
+	query := fmt.Sprintf(`
+		UPDATE %[1]s SET state = 'in_flight', attempts = attempts + 1,
+			lease_until = now() + make_interval(secs => $2), updated_at = now()
+		WHERE (wiki, title) IN (
+			SELECT wiki, title FROM %[1]s
+			WHERE state = 'pending' OR (state = 'in_flight' AND lease_until < now())
+			ORDER BY depth, updated_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING wiki, title, title_id, depth, seed`, f.table.TableName)
+
+	rows, err := f.table.DB().Query(f.table.Context(), query, n, f.lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("[PostgresFrontier] failed to lease: %w", err)
+	}
+	defer rows.Close()
+
+	var items []model.TitleQuery
+	for rows.Next() {
+		var it model.TitleQuery
+		var id, seed *string
+		if err := rows.Scan(&it.Wiki, &it.Title, &id, &it.Depth, &seed); err != nil {
+			return nil, err
+		}
+		it.ID, it.Seed = deref(id), deref(seed)
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+func (f *PostgresFrontier) Done(item model.TitleQuery) error {
+	return f.setState(item, StateDone)
+}
+
+func (f *PostgresFrontier) Release(item model.TitleQuery) error {
+	return f.setState(item, StatePending)
+}
+
+func (f *PostgresFrontier) Fail(item model.TitleQuery, cause error) error {
+	query := fmt.Sprintf(`UPDATE %s SET state = 'failed', last_error = $3, lease_until = NULL, updated_at = now()
+		WHERE wiki = $1 AND title = $2 AND state = 'in_flight'`, f.table.TableName)
+	if _, err := f.table.DB().Exec(f.table.Context(), query, item.Wiki, item.Title, cause.Error()); err != nil {
+		return fmt.Errorf("[PostgresFrontier] failed to mark '%s' %s: %w", item.Title, StateFailed, err)
+	}
+	return nil
+}
+
+func (f *PostgresFrontier) Renew(items ...model.TitleQuery) error {
+	if len(items) == 0 {
+		return nil
+	}
+	wikis, titles := make([]string, len(items)), make([]string, len(items))
+	for i, it := range items {
+		wikis[i], titles[i] = it.Wiki, it.Title
+	}
+	query := fmt.Sprintf(`UPDATE %s SET lease_until = now() + make_interval(secs => $3)
+		WHERE state = 'in_flight' AND (wiki, title) IN (SELECT * FROM unnest($1::text[], $2::text[]))`,
+		f.table.TableName)
+	if _, err := f.table.DB().Exec(f.table.Context(), query, wikis, titles, f.lease.Seconds()); err != nil {
+		return fmt.Errorf("[PostgresFrontier] failed to renew %d lease(s): %w", len(items), err)
+	}
+	return nil
+}
+
+func (f *PostgresFrontier) setState(item model.TitleQuery, state State) error {
+	query := fmt.Sprintf(`UPDATE %s SET state = $3, lease_until = NULL, updated_at = now()
+		WHERE wiki = $1 AND title = $2 AND state = 'in_flight'`, f.table.TableName)
+	if _, err := f.table.DB().Exec(f.table.Context(), query, item.Wiki, item.Title, string(state)); err != nil {
+		return fmt.Errorf("[PostgresFrontier] failed to mark '%s' %s: %w", item.Title, state, err)
+	}
+	return nil
+}
+
+func (f *PostgresFrontier) Counts() (map[State]int64, error) {
+	query := fmt.Sprintf(`SELECT state, COUNT(*) FROM %s GROUP BY state`, f.table.TableName)
+	rows, err := f.table.DB().Query(f.table.Context(), query)
+	if err != nil {
+		return nil, fmt.Errorf("[PostgresFrontier] failed to count: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[State]int64{StatePending: 0, StateInFlight: 0, StateDone: 0, StateFailed: 0}
+	for rows.Next() {
+		var state string
+		var n int64
+		if err := rows.Scan(&state, &n); err != nil {
+			return nil, err
+		}
+		counts[State(state)] = n
+	}
+	return counts, rows.Err()
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,233 @@
+package frontier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+	"wikicrawler/internal/infra/redisclient"
+	"wikicrawler/internal/model"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisFrontier keeps the frontier in these keys under prefix:
+//
+//	items    HASH  wiki|title -> TitleQuery JSON (every title ever pushed)
+//	pending  ZSET  wiki|title, score = depth*1e12 + seq
+//	inflight ZSET  wiki|title, score = lease deadline (unix ms)
+//	done     SET   wiki|title
+//	seq      INT   insertion counter
+//	failed   HASH  wiki|title -> last error
+//
+// Lower depths are leased first and titles of the same depth in FIFO order.
+// State changes run in Lua scripts, so several crawler processes can share it.
+type RedisFrontier struct {
+	client *redis.Client
+	prefix string
+	lease  time.Duration
+	ctx    context.Context
+}
+
+func NewRedisFrontier(rc *redisclient.RedisClient, prefix string, lease time.Duration) *RedisFrontier {
+	return &RedisFrontier{
+		client: rc.GetClient(),
+		prefix: prefix,
+		lease:  lease,
+		ctx:    context.Background(),
+	}
+}
+
+func (f *RedisFrontier) keys() []string {
+	return []string{f.prefix + ":items", f.prefix + ":pending", f.prefix + ":inflight", f.prefix + ":done", f.prefix + ":seq",
+		f.prefix + ":failed"}
+}
+
+// KEYS: items, pending, inflight, done, seq, failed  ARGV: (key, json, depth)...
+var pushScript = redis.NewScript(`
+local added = 0
+for i = 1, #ARGV, 3 do
+	if redis.call('HSETNX', KEYS[1], ARGV[i], ARGV[i+1]) == 1 then
+		local seq = redis.call('INCR', KEYS[5])
+		redis.call('ZADD', KEYS[2], tonumber(ARGV[i+2]) * 1e12 + seq, ARGV[i])
+		added = added + 1
+	end
+end
+return added
+`)
+
+// KEYS: items, pending, inflight, done, seq, failed  ARGV: (key, json, depth)...
+var requeueScript = redis.NewScript(`
+local n = 0
+for i = 1, #ARGV, 3 do
+	local key, depth = ARGV[i], tonumber(ARGV[i+2])
+	local raw = redis.call('HGET', KEYS[1], key)
+	if not raw then
+		redis.call('HSET', KEYS[1], key, ARGV[i+1])
+		redis.call('ZADD', KEYS[2], depth * 1e12 + redis.call('INCR', KEYS[5]), key)
+		n = n + 1
+	elseif redis.call('ZSCORE', KEYS[2], key) and depth < (cjson.decode(raw).Depth or 0) then
+		redis.call('HSET', KEYS[1], key, ARGV[i+1])
+		redis.call('ZADD', KEYS[2], depth * 1e12 + redis.call('INCR', KEYS[5]), key)
+		n = n + 1
+	end
+end
+return n
+`)
+
+// KEYS: items, pending, inflight, done, seq, failed  ARGV: n, lease_ms
+var leaseScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+-- expired leases go back to the end of their depth
+local expired = redis.call('ZRANGEBYSCORE', KEYS[3], '-inf', now)
+for _, title in ipairs(expired) do
+	redis.call('ZREM', KEYS[3], title)
+	local item = cjson.decode(redis.call('HGET', KEYS[1], title))
+	redis.call('ZADD', KEYS[2], (item.Depth or 0) * 1e12 + redis.call('INCR', KEYS[5]), title)
+end
+
+local out = {}
+local popped = redis.call('ZPOPMIN', KEYS[2], tonumber(ARGV[1]))
+for i = 1, #popped, 2 do
+	local title = popped[i]
+	redis.call('ZADD', KEYS[3], now + tonumber(ARGV[2]), title)
+	table.insert(out, redis.call('HGET', KEYS[1], title))
+end
+return out
+`)
+
+// KEYS: items, pending, inflight, done, seq, failed  ARGV: key, state, error
+var setStateScript = redis.NewScript(`
+-- only a title in flight changes state
+if redis.call('ZREM', KEYS[3], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('HDEL', KEYS[6], ARGV[1])
+if ARGV[2] == 'done' then
+	redis.call('SADD', KEYS[4], ARGV[1])
+elseif ARGV[2] == 'failed' then
+	redis.call('HSET', KEYS[6], ARGV[1], ARGV[3])
+else
+	local item = cjson.decode(redis.call('HGET', KEYS[1], ARGV[1]))
+	redis.call('ZADD', KEYS[2], (item.Depth or 0) * 1e12 + redis.call('INCR', KEYS[5]), ARGV[1])
+end
+return 1
+`)
+
+// KEYS: items, pending, inflight, done, seq, failed  ARGV: lease_ms, key...
+var renewScript = redis.NewScript(`
+local t = redis.call('TIME')
+local deadline = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000) + tonumber(ARGV[1])
+for i = 2, #ARGV do
+	redis.call('ZADD', KEYS[3], 'XX', deadline, ARGV[i])
+end
+return 1
+`)
+
+func (f *RedisFrontier) Push(items ...model.TitleQuery) (int, error) {
+	if len(items) == 0 {
+		return 0, nil
+	}
+	args := make([]interface{}, 0, 3*len(items))
+	for _, it := range items {
+		data, err := json.Marshal(it)
+		if err != nil {
+			return 0, fmt.Errorf("[RedisFrontier] failed to encode '%s': %w", it.Title, err)
+		}
+		args = append(args, it.Key(), data, it.Depth)
+	}
+	added, err := pushScript.Run(f.ctx, f.client, f.keys(), args...).Int()
+	if err != nil {
+		return 0, fmt.Errorf("[RedisFrontier] failed to push: %w", err)
+	}
+	return added, nil
+}
+
+func (f *RedisFrontier) Requeue(items ...model.TitleQuery) (int, error) {
+	if len(items) == 0 {
+		return 0, nil
+	}
+	args := make([]interface{}, 0, 3*len(items))
+	for _, it := range items {
+		data, err := json.Marshal(it)
+		if err != nil {
+			return 0, fmt.Errorf("[RedisFrontier] failed to encode '%s': %w", it.Title, err)
+		}
+		args = append(args, it.Key(), data, it.Depth)
+	}
+	n, err := requeueScript.Run(f.ctx, f.client, f.keys(), args...).Int()
+	if err != nil {
+		return 0, fmt.Errorf("[RedisFrontier] failed to requeue: %w", err)
+	}
+	return n, nil
+}
+
+func (f *RedisFrontier) Lease(n int) ([]model.TitleQuery, error) {
+	raw, err := leaseScript.Run(f.ctx, f.client, f.keys(), n, f.lease.Milliseconds()).StringSlice()
+	if err != nil {
+		return nil, fmt.Errorf("[RedisFrontier] failed to lease: %w", err)
+	}
+	items := make([]model.TitleQuery, 0, len(raw))
+	for _, r := range raw {
+		var it model.TitleQuery
+		if err := json.Unmarshal([]byte(r), &it); err != nil {
+			return nil, fmt.Errorf("[RedisFrontier] failed to decode item: %w", err)
+		}
+		items = append(items, it)
+	}
+	return items, nil
+}
+
+func (f *RedisFrontier) Done(item model.TitleQuery) error {
+	return f.setState(item, StateDone, "")
+}
+
+func (f *RedisFrontier) Release(item model.TitleQuery) error {
+	return f.setState(item, StatePending, "")
+}
+
+func (f *RedisFrontier) Fail(item model.TitleQuery, cause error) error {
+	return f.setState(item, StateFailed, cause.Error())
+}
+
+func (f *RedisFrontier) Renew(items ...model.TitleQuery) error {
+	if len(items) == 0 {
+		return nil
+	}
+	args := make([]interface{}, 0, 1+len(items))
+	args = append(args, f.lease.Milliseconds())
+	for _, it := range items {
+		args = append(args, it.Key())
+	}
+	if err := renewScript.Run(f.ctx, f.client, f.keys(), args...).Err(); err != nil {
+		return fmt.Errorf("[RedisFrontier] failed to renew %d lease(s): %w", len(items), err)
+	}
+	return nil
+}
+
+func (f *RedisFrontier) setState(item model.TitleQuery, state State, cause string) error {
+	if err := setStateScript.Run(f.ctx, f.client, f.keys(), item.Key(), string(state), cause).Err(); err != nil {
+		return fmt.Errorf("[RedisFrontier] failed to mark '%s' %s: %w", item.Title, state, err)
+	}
+	return nil
+}
+
+func (f *RedisFrontier) Counts() (map[State]int64, error) {
+	k := f.keys()
+	pipe := f.client.Pipeline()
+	pending := pipe.ZCard(f.ctx, k[1])
+	inflight := pipe.ZCard(f.ctx, k[2])
+	done := pipe.SCard(f.ctx, k[3])
+	failed := pipe.HLen(f.ctx, k[5])
+	if _, err := pipe.Exec(f.ctx); err != nil {
+		return nil, fmt.Errorf("[RedisFrontier] failed to count: %w", err)
+	}
+	return map[State]int64{
+		StatePending:  pending.Val(),
+		StateInFlight: inflight.Val(),
+		StateDone:     done.Val(),
+		StateFailed:   failed.Val(),
+	}, nil
+}
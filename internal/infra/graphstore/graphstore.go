@@ -0,0 +1,97 @@
+package graphstore
+
+import (
+	"context"
+	"errors"
+	"wikicrawler/internal/infra/postgresclient/tables"
+	"wikicrawler/internal/model"
+)
+
+// Title statuses and alias kinds, the same values in every backend.
+const (
+	StatusOK      = tables.TitleStatusOK
+	StatusMissing = tables.TitleStatusMissing
+	StatusInvalid = tables.TitleStatusInvalid
+
+	AliasNormalized = tables.AliasNormalized
+	AliasRedirect   = tables.AliasRedirect
+)
+
+// ErrNotFound is returned by TitleIDByName for a title that is not stored.
+var ErrNotFound = errors.New("title not found")
+
+// Counts is the size of the stored graph.
+type Counts struct {
+	Titles   int64
+	Pairs    int64
+	ByStatus map[string]int64 // titles per status
+}
+
+// Graph is the link graph: titles keyed by (wiki, name), directed edges
+// between title IDs, and aliases (redirects, normalized spellings) that point
+// at a canonical title.
+type Graph interface {
+	// InsertTitle stores q and its (seed, depth) unless its ID or (wiki, name) is
+	// taken, and reports whether it did.
+	InsertTitle(q model.TitleQuery) (bool, error)
+	// UpsertTitles stores the titles (of one wiki) that are new and the (seed, depth)
+	// of every one; a name may come once per seed. The lowest depth per seed is
+	// kept, and a title's own depth and seed are those of its closest seed. It
+	// returns name -> ID of every title, including the ones stored before, and
+	// the names that are new to a seed or closer to it than before.
+	UpsertTitles(titles []model.TitleQuery) (map[string]string, map[string]bool, error)
+	// TitleSeeds returns seed -> depth of every seed the title was found from.
+	TitleSeeds(id string) (map[string]int, error)
+	// TitleIDByName returns the ID of (wiki, name), or an error wrapping ErrNotFound.
+	TitleIDByName(wiki, name string) (string, error)
+	// TitleNames returns ID -> name for the ids that are stored.
+	TitleNames(ids []string) (map[string]string, error)
+	// TitlesWithinHops lists the titles found from seed at most maxDepth hops away, by depth then name,
+	// whether or not another seed is closer to them.
+	TitlesWithinHops(wiki, seed string, maxDepth int) ([]string, error)
+	// SetTitleStatus marks a title ok, missing or invalid.
+	SetTitleStatus(id, status string) error
+	// DeleteTitle removes a title and its aliases; it must have no edges left.
+	DeleteTitle(id string) error
+
+	// AddEdges stores src -> dst for every dst, counting an occurrence on edges
+	// that exist already, and returns how many edges are new.
+	AddEdges(src string, dsts []string) (int64, error)
+	// Neighbors returns src -> the targets of its edges, for every src in srcIDs.
+	Neighbors(srcIDs []string) (map[string][]string, error)
+	// RepointTitle moves every edge, seed depth and alias of fromID to toID, merging them
+	// into the ones toID already has.
+	RepointTitle(fromID, toID string) error
+	// ForEachEdge calls fn with (wiki, source name, target name) of every edge.
+	ForEachEdge(fn func(wiki, src, dst string) error) error
+
+	// UpsertAlias points alias at titleID, replacing what it pointed at before.
+	UpsertAlias(wiki, alias, titleID, kind string) error
+	// ResolveAliases returns alias -> title ID for the names that are known aliases.
+	ResolveAliases(wiki string, names []string) (map[string]string, error)
+
+	// Counts returns the number of titles and edges.
+	Counts() (Counts, error)
+}
+
+// GraphStore is a Graph that can also apply several changes at once.
+type GraphStore interface {
+	Graph
+	// WithTx runs fn with a Graph whose changes are kept only if fn returns nil.
+	// fn may run more than once (e.g. after a deadlock), so it must not have
+	// side effects outside tx.
+	WithTx(ctx context.Context, fn func(tx Graph) error) error
+}
+
+// ResolveTitle returns the ID of a title, following a redirect or normalized
+// alias ("Donald J. Trump") to its canonical title when there is no such node.
+func ResolveTitle(g Graph, wiki, name string) (string, error) {
+	id, err := g.TitleIDByName(wiki, name)
+	if err == nil || !errors.Is(err, ErrNotFound) {
+		return id, err
+	}
+	if ids, aerr := g.ResolveAliases(wiki, []string{name}); aerr == nil && ids[name] != "" {
+		return ids[name], nil
+	}
+	return "", err
+}
@@ -0,0 +1,474 @@
+package graphstore
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"wikicrawler/internal/model"
+)
+
+// MemoryGraphStore keeps the graph in maps, for tests and small offline
+// crawls; nothing survives the process. It checks what the Postgres schema
+// enforces (edges between stored titles, unique names), so code that works
+// against it works against Postgres too.
+type MemoryGraphStore struct {
+	mu sync.Mutex
+	g  *memGraph
+}
+
+func NewMemoryGraphStore() *MemoryGraphStore {
+	return &MemoryGraphStore{g: &memGraph{
+		titles:  make(map[string]memTitle),
+		byName:  make(map[memKey]string),
+		edges:   make(map[string]map[string]int),
+		aliases: make(map[memKey]string),
+		seeds:   make(map[memSeedKey]int),
+	}}
+}
+
+// WithTx runs fn while holding the store; when fn fails, every change it made is undone.
+func (s *MemoryGraphStore) WithTx(ctx context.Context, fn func(tx Graph) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.g.undo = []func(){}
+	err := fn(s.g)
+	if err != nil {
+		for i := len(s.g.undo) - 1; i >= 0; i-- {
+			s.g.undo[i]()
+		}
+	}
+	s.g.undo = nil
+	return err
+}
+
+func (s *MemoryGraphStore) InsertTitle(q model.TitleQuery) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.g.InsertTitle(q)
+}
+
+func (s *MemoryGraphStore) UpsertTitles(titles []model.TitleQuery) (map[string]string, map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.g.UpsertTitles(titles)
+}
+
+func (s *MemoryGraphStore) TitleSeeds(id string) (map[string]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.g.TitleSeeds(id)
+}
+
+func (s *MemoryGraphStore) TitleIDByName(wiki, name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.g.TitleIDByName(wiki, name)
+}
+
+func (s *MemoryGraphStore) TitleNames(ids []string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.g.TitleNames(ids)
+}
+
+func (s *MemoryGraphStore) TitlesWithinHops(wiki, seed string, maxDepth int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.g.TitlesWithinHops(wiki, seed, maxDepth)
+}
+
+func (s *MemoryGraphStore) SetTitleStatus(id, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.g.SetTitleStatus(id, status)
+}
+
+func (s *MemoryGraphStore) DeleteTitle(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.g.DeleteTitle(id)
+}
+
+func (s *MemoryGraphStore) AddEdges(src string, dsts []string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.g.AddEdges(src, dsts)
+}
+
+func (s *MemoryGraphStore) Neighbors(srcIDs []string) (map[string][]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.g.Neighbors(srcIDs)
+}
+
+func (s *MemoryGraphStore) RepointTitle(fromID, toID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.g.RepointTitle(fromID, toID)
+}
+
+// ForEachEdge calls fn while holding the store, so fn must not use it.
+func (s *MemoryGraphStore) ForEachEdge(fn func(wiki, src, dst string) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.g.ForEachEdge(fn)
+}
+
+func (s *MemoryGraphStore) UpsertAlias(wiki, alias, titleID, kind string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.g.UpsertAlias(wiki, alias, titleID, kind)
+}
+
+func (s *MemoryGraphStore) ResolveAliases(wiki string, names []string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.g.ResolveAliases(wiki, names)
+}
+
+func (s *MemoryGraphStore) Counts() (Counts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.g.Counts()
+}
+
+type memKey struct{ wiki, name string }
+
+type memSeedKey struct{ id, seed string }
+
+type memTitle struct {
+	id, wiki, name, seed, status string
+	depth                        int
+}
+
+// memGraph is the unlocked graph behind MemoryGraphStore. Every change goes
+// through a set* method, which records how to undo it while a WithTx runs.
+type memGraph struct {
+	titles  map[string]memTitle       // id -> title
+	byName  map[memKey]string         // (wiki, name) -> id
+	edges   map[string]map[string]int // src -> dst -> occurrences
+	aliases map[memKey]string         // (wiki, alias) -> id
+	seeds   map[memSeedKey]int        // (id, seed) -> depth
+	undo    []func()                  // nil outside WithTx
+}
+
+func (g *memGraph) setTitle(id string, t memTitle, ok bool) {
+	if g.undo != nil {
+		old, had := g.titles[id]
+		g.undo = append(g.undo, func() { g.setTitle(id, old, had) })
+	}
+	if ok {
+		g.titles[id] = t
+	} else {
+		delete(g.titles, id)
+	}
+}
+
+func (g *memGraph) setName(k memKey, id string) {
+	if g.undo != nil {
+		old := g.byName[k]
+		g.undo = append(g.undo, func() { g.setName(k, old) })
+	}
+	if id != "" {
+		g.byName[k] = id
+	} else {
+		delete(g.byName, k)
+	}
+}
+
+func (g *memGraph) setEdge(src, dst string, n int) {
+	if g.undo != nil {
+		old := g.edges[src][dst]
+		g.undo = append(g.undo, func() { g.setEdge(src, dst, old) })
+	}
+	if n > 0 {
+		if g.edges[src] == nil {
+			g.edges[src] = make(map[string]int)
+		}
+		g.edges[src][dst] = n
+	} else if g.edges[src] != nil {
+		delete(g.edges[src], dst)
+		if len(g.edges[src]) == 0 {
+			delete(g.edges, src)
+		}
+	}
+}
+
+func (g *memGraph) setAlias(k memKey, id string) {
+	if g.undo != nil {
+		old := g.aliases[k]
+		g.undo = append(g.undo, func() { g.setAlias(k, old) })
+	}
+	if id != "" {
+		g.aliases[k] = id
+	} else {
+		delete(g.aliases, k)
+	}
+}
+
+func (g *memGraph) setSeed(k memSeedKey, depth int, ok bool) {
+	if g.undo != nil {
+		old, had := g.seeds[k]
+		g.undo = append(g.undo, func() { g.setSeed(k, old, had) })
+	}
+	if ok {
+		g.seeds[k] = depth
+	} else {
+		delete(g.seeds, k)
+	}
+}
+
+// addSeed keeps the lower depth of (id, seed) and reports whether it is new or lower.
+func (g *memGraph) addSeed(id, seed string, depth int) bool {
+	k := memSeedKey{id, seed}
+	if old, ok := g.seeds[k]; ok && old <= depth {
+		return false
+	}
+	g.setSeed(k, depth, true)
+	return true
+}
+
+func (g *memGraph) InsertTitle(q model.TitleQuery) (bool, error) {
+	if q.ID == "" {
+		return false, fmt.Errorf("[MemoryGraphStore] title '%s' has no ID", q.Title)
+	}
+	k := memKey{q.Wiki, q.Title}
+	if _, ok := g.titles[q.ID]; ok {
+		return false, nil
+	}
+	if _, ok := g.byName[k]; ok {
+		return false, nil
+	}
+	g.setTitle(q.ID, memTitle{id: q.ID, wiki: q.Wiki, name: q.Title, seed: q.Seed, status: StatusOK, depth: q.Depth}, true)
+	g.setName(k, q.ID)
+	g.addSeed(q.ID, q.Seed, q.Depth)
+	return true, nil
+}
+
+func (g *memGraph) UpsertTitles(titles []model.TitleQuery) (map[string]string, map[string]bool, error) {
+	ids := make(map[string]string, len(titles))
+	reached := make(map[string]bool)
+	for _, q := range titles {
+		id, ok := g.byName[memKey{q.Wiki, q.Title}]
+		if !ok {
+			inserted, err := g.InsertTitle(q)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !inserted {
+				return nil, nil, fmt.Errorf("[MemoryGraphStore] ID %s of '%s' is taken", q.ID, q.Title)
+			}
+			ids[q.Title] = q.ID
+			reached[q.Title] = true
+			continue
+		}
+
+		ids[q.Title] = id
+		if t := g.titles[id]; q.Depth < t.depth {
+			t.depth, t.seed = q.Depth, q.Seed
+			g.setTitle(id, t, true)
+		}
+		if g.addSeed(id, q.Seed, q.Depth) {
+			reached[q.Title] = true
+		}
+	}
+	return ids, reached, nil
+}
+
+func (g *memGraph) TitleSeeds(id string) (map[string]int, error) {
+	seeds := make(map[string]int)
+	for k, depth := range g.seeds {
+		if k.id == id {
+			seeds[k.seed] = depth
+		}
+	}
+	return seeds, nil
+}
+
+func (g *memGraph) TitleIDByName(wiki, name string) (string, error) {
+	id, ok := g.byName[memKey{wiki, name}]
+	if !ok {
+		return "", fmt.Errorf("[MemoryGraphStore] '%s' (%s): %w", name, wiki, ErrNotFound)
+	}
+	return id, nil
+}
+
+func (g *memGraph) TitleNames(ids []string) (map[string]string, error) {
+	names := make(map[string]string, len(ids))
+	for _, id := range ids {
+		if t, ok := g.titles[id]; ok {
+			names[id] = t.name
+		}
+	}
+	return names, nil
+}
+
+func (g *memGraph) TitlesWithinHops(wiki, seed string, maxDepth int) ([]string, error) {
+	type found struct {
+		name  string
+		depth int
+	}
+	var all []found
+	for k, depth := range g.seeds {
+		if t := g.titles[k.id]; k.seed == seed && t.wiki == wiki && depth <= maxDepth {
+			all = append(all, found{t.name, depth})
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].depth != all[j].depth {
+			return all[i].depth < all[j].depth
+		}
+		return all[i].name < all[j].name
+	})
+	names := make([]string, len(all))
+	for i, f := range all {
+		names[i] = f.name
+	}
+	return names, nil
+}
+
+func (g *memGraph) SetTitleStatus(id, status string) error {
+	switch status {
+	case StatusOK, StatusMissing, StatusInvalid:
+	default:
+		return fmt.Errorf("[MemoryGraphStore] unknown status %q", status)
+	}
+	t, ok := g.titles[id]
+	if !ok {
+		return nil // UPDATE of no row
+	}
+	t.status = status
+	g.setTitle(id, t, true)
+	return nil
+}
+
+func (g *memGraph) DeleteTitle(id string) error {
+	t, ok := g.titles[id]
+	if !ok {
+		return nil
+	}
+	for src, dsts := range g.edges {
+		if _, in := dsts[id]; src == id || in {
+			return fmt.Errorf("[MemoryGraphStore] '%s' still has edges", t.name)
+		}
+	}
+	for k, to := range g.aliases {
+		if to == id {
+			g.setAlias(k, "")
+		}
+	}
+	for k := range g.seeds {
+		if k.id == id {
+			g.setSeed(k, 0, false)
+		}
+	}
+	g.setName(memKey{t.wiki, t.name}, "")
+	g.setTitle(id, memTitle{}, false)
+	return nil
+}
+
+func (g *memGraph) AddEdges(src string, dsts []string) (int64, error) {
+	if _, ok := g.titles[src]; !ok {
+		return 0, fmt.Errorf("[MemoryGraphStore] unknown source title %s", src)
+	}
+	dsts = append([]string(nil), dsts...)
+	sort.Strings(dsts)
+	dsts = slices.Compact(dsts)
+
+	var inserted int64
+	for _, dst := range dsts {
+		if _, ok := g.titles[dst]; !ok {
+			return inserted, fmt.Errorf("[MemoryGraphStore] unknown target title %s", dst)
+		}
+		n := g.edges[src][dst]
+		if n == 0 {
+			inserted++
+		}
+		g.setEdge(src, dst, n+1)
+	}
+	return inserted, nil
+}
+
+func (g *memGraph) Neighbors(srcIDs []string) (map[string][]string, error) {
+	neighbors := make(map[string][]string, len(srcIDs))
+	for _, src := range srcIDs {
+		for dst := range g.edges[src] {
+			neighbors[src] = append(neighbors[src], dst)
+		}
+	}
+	return neighbors, nil
+}
+
+func (g *memGraph) RepointTitle(fromID, toID string) error {
+	// outgoing edges first, then incoming ones, like PairsTable.RepointTitle
+	for dst, n := range g.edges[fromID] {
+		g.setEdge(toID, dst, g.edges[toID][dst]+n)
+		g.setEdge(fromID, dst, 0)
+	}
+	for src, dsts := range g.edges {
+		if n, ok := dsts[fromID]; ok {
+			g.setEdge(src, toID, dsts[toID]+n)
+			g.setEdge(src, fromID, 0)
+		}
+	}
+	for k, depth := range g.seeds {
+		if k.id == fromID {
+			g.addSeed(toID, k.seed, depth)
+		}
+	}
+	for k, id := range g.aliases {
+		if id == fromID {
+			g.setAlias(k, toID)
+		}
+	}
+	return nil
+}
+
+func (g *memGraph) ForEachEdge(fn func(wiki, src, dst string) error) error {
+	for src, dsts := range g.edges {
+		s := g.titles[src]
+		for dst := range dsts {
+			if err := fn(s.wiki, s.name, g.titles[dst].name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (g *memGraph) UpsertAlias(wiki, alias, titleID, kind string) error {
+	if kind != AliasNormalized && kind != AliasRedirect {
+		return fmt.Errorf("[MemoryGraphStore] unknown alias kind %q", kind)
+	}
+	if _, ok := g.titles[titleID]; !ok {
+		return fmt.Errorf("[MemoryGraphStore] alias '%s' of unknown title %s", alias, titleID)
+	}
+	g.setAlias(memKey{wiki, alias}, titleID)
+	return nil
+}
+
+func (g *memGraph) ResolveAliases(wiki string, names []string) (map[string]string, error) {
+	ids := make(map[string]string)
+	for _, name := range names {
+		if id, ok := g.aliases[memKey{wiki, name}]; ok {
+			ids[name] = id
+		}
+	}
+	return ids, nil
+}
+
+func (g *memGraph) Counts() (Counts, error) {
+	c := Counts{Titles: int64(len(g.titles)), ByStatus: make(map[string]int64)}
+	for _, dsts := range g.edges {
+		c.Pairs += int64(len(dsts))
+	}
+	for _, t := range g.titles {
+		c.ByStatus[t.status]++
+	}
+	return c, nil
+}
@@ -0,0 +1,161 @@
+package graphstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	dbclient "wikicrawler/internal/infra/postgresclient"
+	"wikicrawler/internal/infra/postgresclient/tables"
+	"wikicrawler/internal/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PostgresGraphStore keeps the graph in the titles, pairs and aliases tables.
+type PostgresGraphStore struct {
+	db      *dbclient.PostgresClient
+	titles  *tables.TitlesTable
+	pairs   *tables.PairsTable
+	aliases *tables.AliasesTable
+	seeds   *tables.TitleSeedsTable
+	ctx     context.Context
+	tx      pgx.Tx // set on the Graph passed to a WithTx callback
+}
+
+// NewPostgresGraphStore expects the tables to exist, see WikiStore.EnsureTables.
+func NewPostgresGraphStore(db *dbclient.PostgresClient) *PostgresGraphStore {
+	return &PostgresGraphStore{
+		db:      db,
+		titles:  tables.NewTitlesTable(db),
+		pairs:   tables.NewPairsTable(db),
+		aliases: tables.NewAliasesTable(db),
+		seeds:   tables.NewTitleSeedsTable(db),
+	}
+}
+
+// WithTx runs fn in one Postgres transaction (see PostgresClient.WithTx).
+// Called on the Graph of a running transaction, fn joins that transaction.
+func (s *PostgresGraphStore) WithTx(ctx context.Context, fn func(tx Graph) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+	return s.db.WithTx(ctx, func(tx pgx.Tx) error {
+		return fn(&PostgresGraphStore{
+			db:      s.db,
+			titles:  s.titles.Tx(ctx, tx),
+			pairs:   s.pairs.Tx(ctx, tx),
+			aliases: s.aliases.Tx(ctx, tx),
+			seeds:   s.seeds.Tx(ctx, tx),
+			ctx:     ctx,
+			tx:      tx,
+		})
+	})
+}
+
+// Tx returns the transaction of a Graph passed to a WithTx callback, so other
+// tables (e.g. the frontier) can join it; tx is nil outside WithTx.
+func (s *PostgresGraphStore) Tx() (context.Context, pgx.Tx) {
+	return s.ctx, s.tx
+}
+
+func (s *PostgresGraphStore) InsertTitle(q model.TitleQuery) (bool, error) {
+	inserted, err := s.titles.Insert(tables.TitleFromQuery(q))
+	if err != nil || !inserted {
+		return inserted, err
+	}
+	_, err = s.seeds.UpsertMany([]tables.TitleSeed{{TitleID: q.ID, Seed: q.Seed, Depth: q.Depth}})
+	return true, err
+}
+
+func (s *PostgresGraphStore) UpsertTitles(titles []model.TitleQuery) (map[string]string, map[string]bool, error) {
+	ids, _, err := s.titles.UpsertMany(titles)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows := make([]tables.TitleSeed, 0, len(titles))
+	for _, q := range titles {
+		rows = append(rows, tables.TitleSeed{TitleID: ids[q.Title], Seed: q.Seed, Depth: q.Depth})
+	}
+	reachedIDs, err := s.seeds.UpsertMany(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	reached := make(map[string]bool, len(reachedIDs))
+	for name, id := range ids {
+		if reachedIDs[id] {
+			reached[name] = true
+		}
+	}
+	return ids, reached, nil
+}
+
+func (s *PostgresGraphStore) TitleSeeds(id string) (map[string]int, error) {
+	return s.seeds.GetByTitle(id)
+}
+
+func (s *PostgresGraphStore) TitleIDByName(wiki, name string) (string, error) {
+	id, err := s.titles.GetIDByName(wiki, name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("[PostgresGraphStore] '%s' (%s): %w", name, wiki, ErrNotFound)
+	}
+	return id, err
+}
+
+func (s *PostgresGraphStore) TitleNames(ids []string) (map[string]string, error) {
+	return s.titles.GetNamesByIDs(ids)
+}
+
+func (s *PostgresGraphStore) TitlesWithinHops(wiki, seed string, maxDepth int) ([]string, error) {
+	return s.seeds.GetNamesWithinHops(wiki, seed, maxDepth)
+}
+
+func (s *PostgresGraphStore) SetTitleStatus(id, status string) error {
+	return s.titles.SetStatus(id, status)
+}
+
+func (s *PostgresGraphStore) DeleteTitle(id string) error {
+	return s.titles.DeleteByID(id)
+}
+
+func (s *PostgresGraphStore) AddEdges(src string, dsts []string) (int64, error) {
+	return s.pairs.InsertMany(src, dsts)
+}
+
+func (s *PostgresGraphStore) Neighbors(srcIDs []string) (map[string][]string, error) {
+	return s.pairs.GetNeighbors(srcIDs)
+}
+
+func (s *PostgresGraphStore) RepointTitle(fromID, toID string) error {
+	if err := s.pairs.RepointTitle(fromID, toID); err != nil {
+		return err
+	}
+	if err := s.seeds.Repoint(fromID, toID); err != nil {
+		return err
+	}
+	return s.aliases.Repoint(fromID, toID)
+}
+
+func (s *PostgresGraphStore) ForEachEdge(fn func(wiki, src, dst string) error) error {
+	return s.pairs.ForEachEdge(fn)
+}
+
+func (s *PostgresGraphStore) UpsertAlias(wiki, alias, titleID, kind string) error {
+	return s.aliases.Upsert(wiki, alias, titleID, kind)
+}
+
+func (s *PostgresGraphStore) ResolveAliases(wiki string, names []string) (map[string]string, error) {
+	return s.aliases.ResolveMany(wiki, names)
+}
+
+func (s *PostgresGraphStore) Counts() (Counts, error) {
+	var c Counts
+	var err error
+	if c.Titles, err = s.titles.Count(); err != nil {
+		return c, err
+	}
+	if c.Pairs, err = s.pairs.Count(); err != nil {
+		return c, err
+	}
+	c.ByStatus, err = s.titles.CountByStatus()
+	return c, err
+}
@@ -1,9 +1,12 @@
 package dbclient
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
+
+	"github.com/jackc/pgx/v5"
 )
 
 type BaseTable struct {
@@ -11,6 +14,36 @@ type BaseTable struct {
 	TableName   string
 	Columns     map[string]string // column_name -> type (VD: "id": "SERIAL PRIMARY KEY")
 	Constraints []string          // danh sách constraint ở mức table (FOREIGN KEY, UNIQUE, CHECK, ...)
+	Querier     Querier           // nil = Client.Pool; một pgx.Tx khi table được lấy qua InTx
+	Ctx         context.Context   // context của mọi truy vấn, nil = context.Background()
+}
+
+// DB trả về nơi các truy vấn của table chạy: transaction nếu có, không thì pool
+func (bt *BaseTable) DB() Querier {
+	if bt.Querier != nil {
+		return bt.Querier
+	}
+	return bt.Client.Pool
+}
+
+// Context trả về context dùng cho các truy vấn của table
+func (bt *BaseTable) Context() context.Context {
+	if bt.Ctx != nil {
+		return bt.Ctx
+	}
+	return context.Background()
+}
+
+// WithContext trả về bản sao của table chạy mọi truy vấn với ctx (hủy / timeout)
+func (bt BaseTable) WithContext(ctx context.Context) BaseTable {
+	bt.Ctx = ctx
+	return bt
+}
+
+// InTx trả về bản sao của table chạy mọi truy vấn trong tx (xem PostgresClient.WithTx)
+func (bt BaseTable) InTx(ctx context.Context, tx pgx.Tx) BaseTable {
+	bt.Querier, bt.Ctx = tx, ctx
+	return bt
 }
 
 // CreateTable tạo bảng dựa trên metadata
@@ -40,14 +73,14 @@ func (bt *BaseTable) CreateTable() {
 		strings.Join(cols, ", "),
 	)
 
-	if _, err := bt.Client.DB.Exec(query); err != nil {
+	if _, err := bt.Client.Pool.Exec(bt.Context(), query); err != nil {
 		log.Fatalf("❌ Lỗi tạo bảng %s: %v", bt.TableName, err)
 	}
 	log.Printf("✅ Bảng %s sẵn sàng.", bt.TableName)
 
 	// Execute post-create queries (indexes, alter table, etc.)
 	for _, q := range postQueries {
-		if _, err := bt.Client.DB.Exec(q); err != nil {
+		if _, err := bt.Client.Pool.Exec(bt.Context(), q); err != nil {
 			log.Printf("⚠️ Không thể thực thi truy vấn sau tạo bảng (%s): %v", q, err)
 		} else {
 			log.Printf("✅ Đã thực thi truy vấn sau tạo bảng: %s", q)
@@ -57,6 +90,12 @@ func (bt *BaseTable) CreateTable() {
 
 // Insert thêm dữ liệu vào bảng, an toàn với duplicate key
 func (bt *BaseTable) Insert(values map[string]interface{}) error {
+	_, err := bt.InsertIfNotExists(values)
+	return err
+}
+
+// InsertIfNotExists giống Insert nhưng cho biết record có thực sự được thêm hay đã tồn tại
+func (bt *BaseTable) InsertIfNotExists(values map[string]interface{}) (bool, error) {
 	cols := []string{}
 	vals := []interface{}{}
 	placeholders := []string{}
@@ -76,101 +115,56 @@ func (bt *BaseTable) Insert(values map[string]interface{}) error {
 		strings.Join(placeholders, ", "),
 	)
 
-	_, err := bt.Client.DB.Exec(query, vals...)
+	tag, err := bt.DB().Exec(bt.Context(), query, vals...)
 	if err != nil {
 		log.Printf("❌ Lỗi insert vào %s: %v", bt.TableName, err)
-		return err
+		return false, err
 	}
-
+	n := tag.RowsAffected()
 	log.Printf("✅ Insert (hoặc bỏ qua nếu trùng) thành công vào %s", bt.TableName)
-	return nil
+	return n > 0, nil
 }
 
-// GetAll lấy tất cả dữ liệu trong table và trả về []map[string]interface{}
+// GetAll lấy tất cả dữ liệu trong table và trả về []map[string]interface{}.
+// Chỉ dùng cho table nhỏ; table lớn thì duyệt Select(...).Rows() hoặc phân trang bằng Page.
 func (bt *BaseTable) GetAll() ([]map[string]interface{}, error) {
-	query := fmt.Sprintf(`SELECT * FROM %s`, bt.TableName)
-	rows, err := bt.Client.DB.Query(query)
-	if err != nil {
-		return nil, fmt.Errorf("❌ lỗi query %s: %w", bt.TableName, err)
-	}
-	defer rows.Close()
-
-	cols, err := rows.Columns()
-	if err != nil {
-		return nil, err
-	}
-
-	var results []map[string]interface{}
-
-	for rows.Next() {
-		// Chuẩn bị mảng giá trị
-		values := make([]interface{}, len(cols))
-		valuePtrs := make([]interface{}, len(cols))
-		for i := range cols {
-			valuePtrs[i] = &values[i]
-		}
-
-		// Scan vào valuePtrs
-		if err := rows.Scan(valuePtrs...); err != nil {
-			return nil, err
-		}
+	return bt.Select().All()
+}
 
-		// Đưa vào map
-		rowData := make(map[string]interface{})
-		for i, col := range cols {
-			val := values[i]
-			if b, ok := val.([]byte); ok {
-				rowData[col] = string(b)
-			} else {
-				rowData[col] = val
-			}
-		}
-		results = append(results, rowData)
+// Count đếm số bản ghi trong table
+func (bt *BaseTable) Count() (int64, error) {
+	var n int64
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s`, bt.TableName)
+	if err := bt.DB().QueryRow(bt.Context(), query).Scan(&n); err != nil {
+		return 0, fmt.Errorf("❌ lỗi đếm %s: %w", bt.TableName, err)
 	}
-
-	return results, nil
+	return n, nil
 }
 
 // GetRecordByKey lấy 1 bản ghi theo cột khóa (VD: id, name, ...)
 func (bt *BaseTable) GetRecordByKey(key string, value interface{}) (map[string]interface{}, error) {
+	if !bt.HasColumn(key) {
+		return nil, fmt.Errorf("❌ %s không có cột %q", bt.TableName, key)
+	}
 	query := fmt.Sprintf(`SELECT * FROM %s WHERE %s = $1 LIMIT 1`, bt.TableName, key)
 
-	row := bt.Client.DB.QueryRow(query, value)
-	cols, err := bt.Client.DB.Query(fmt.Sprintf(`SELECT column_name FROM information_schema.columns WHERE table_name='%s'`, bt.TableName))
+	// Một lần Query duy nhất: trong transaction, một kết nối không mở được truy vấn
+	// thứ hai khi kết quả của truy vấn trước chưa đọc xong
+	rows, err := bt.DB().Query(bt.Context(), query, value)
 	if err != nil {
-		return nil, fmt.Errorf("❌ không thể lấy danh sách cột cho %s: %w", bt.TableName, err)
+		return nil, fmt.Errorf("❌ lỗi query %s: %w", bt.TableName, err)
 	}
-	defer cols.Close()
+	defer rows.Close()
 
-	var columnNames []string
-	for cols.Next() {
-		var name string
-		if err := cols.Scan(&name); err != nil {
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
 			return nil, err
 		}
-		columnNames = append(columnNames, name)
-	}
-
-	values := make([]interface{}, len(columnNames))
-	valuePtrs := make([]interface{}, len(columnNames))
-	for i := range columnNames {
-		valuePtrs[i] = &values[i]
-	}
-
-	// QueryRow.Scan needs exact number of columns known at compile time,
-	// so we dynamically scan based on metadata
-	if err := row.Scan(valuePtrs...); err != nil {
-		return nil, fmt.Errorf("❌ không tìm thấy record có %s = %v: %w", key, value, err)
+		return nil, fmt.Errorf("❌ không tìm thấy record có %s = %v: %w", key, value, pgx.ErrNoRows)
 	}
-
-	record := make(map[string]interface{})
-	for i, col := range columnNames {
-		val := values[i]
-		if b, ok := val.([]byte); ok {
-			record[col] = string(b)
-		} else {
-			record[col] = val
-		}
+	record, err := rowMap(rows)
+	if err != nil {
+		return nil, err
 	}
 
 	log.Printf("✅ Lấy thành công record từ %s theo %s = %v", bt.TableName, key, value)
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	dbclient "wikicrawler/internal/infra/postgresclient"
@@ -8,19 +9,23 @@ import (
 )
 
 func main() {
-	client := dbclient.NewPostgresClient(dbclient.PostGresConfig{
+	ctx := context.Background()
+	client, err := dbclient.NewPostgresClient(ctx, dbclient.PostGresConfig{
 		Host:     "localhost", // IP
 		Port:     "5432",      // Port
 		User:     "taopq",     // user_name
 		Password: "123456a@",  // password
 		DBname:   "wikidb",    // db
 	})
+	if err != nil {
+		log.Fatal(err)
+	}
 	defer client.Close()
 	////////////////////////////////////////////////////////////////////////////////////
 	// Tạo bảng Titles
 	titlesTable := tables.NewTitlesTable(client)
 
-	if !client.SearchTable(titlesTable.TableName) {
+	if !client.SearchTable(ctx, titlesTable.TableName) {
 		fmt.Printf("%s NOT EXIST - CREATION PROCESS STARTING\n", titlesTable.TableName)
 		titlesTable.CreateTable()
 	} else {
@@ -28,18 +33,18 @@ func main() {
 	}
 
 	// Lấy tất cả Domains
-	rows, err := titlesTable.GetAll()
+	titles, err := titlesTable.List()
 	if err != nil {
 		log.Fatal(err)
 	}
-	for _, row := range rows {
-		fmt.Println(row)
+	for _, title := range titles {
+		fmt.Printf("%+v\n", title)
 	}
 	////////////////////////////////////////////////////////////////////////////////////
 	// Tạo bảng Pair Type
 	pairsTable := tables.NewPairsTable(client)
 
-	if !client.SearchTable(pairsTable.TableName) {
+	if !client.SearchTable(ctx, pairsTable.TableName) {
 		fmt.Printf("%s NOT EXIST - CREATION PROCESS STARTING\n", pairsTable.TableName)
 		pairsTable.CreateTable()
 	} else {
@@ -47,11 +52,11 @@ func main() {
 	}
 
 	// Lấy tất cả Entity Type
-	rows, err = pairsTable.GetAll()
+	pairs, err := pairsTable.List()
 	if err != nil {
 		log.Fatal(err)
 	}
-	for _, row := range rows {
-		fmt.Println(row)
+	for _, pair := range pairs {
+		fmt.Printf("%+v\n", pair)
 	}
 }
@@ -0,0 +1,216 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+	dbclient "wikicrawler/internal/infra/postgresclient"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const TableName = "schema_migrations"
+
+// lockKey là khóa pg_advisory_xact_lock: hai process migrate cùng lúc sẽ chạy lần lượt
+const lockKey = 0x77696b69 // "wiki"
+
+// ErrIrreversible is returned by Down for a migration without a Down step.
+var ErrIrreversible = errors.New("migration cannot be rolled back")
+
+// Migration is one versioned schema change. Up and Down are plain SQL and may
+// hold several statements; an empty Down means the change cannot be undone.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is a migration and when it was applied (nil = pending).
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrator applies and rolls back migrations, one transaction per step, and
+// records the applied versions in schema_migrations.
+type Migrator struct {
+	client     *dbclient.PostgresClient
+	migrations []Migration
+	ctx        context.Context
+}
+
+// NewMigrator checks that versions are unique and positive and orders them.
+func NewMigrator(client *dbclient.PostgresClient, migrations []Migration) (*Migrator, error) {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i, m := range sorted {
+		if m.Version <= 0 {
+			return nil, fmt.Errorf("[Migrator] migration %q has version %d, want > 0", m.Name, m.Version)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("[Migrator] version %d is used twice", m.Version)
+		}
+	}
+	return &Migrator{client: client, migrations: sorted, ctx: context.Background()}, nil
+}
+
+// Latest is the highest known version.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) ensureTable() error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		version    INT PRIMARY KEY,
+		name       VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT now()
+	)`, TableName)
+	if _, err := m.client.Pool.Exec(m.ctx, query); err != nil {
+		return fmt.Errorf("[Migrator] failed to create %s: %w", TableName, err)
+	}
+	return nil
+}
+
+// applied returns version -> applied_at of every applied migration.
+func applied(ctx context.Context, q dbclient.Querier) (map[int]time.Time, error) {
+	rows, err := q.Query(ctx, fmt.Sprintf(`SELECT version, applied_at FROM %s`, TableName))
+	if err != nil {
+		return nil, fmt.Errorf("[Migrator] failed to read %s: %w", TableName, err)
+	}
+	defer rows.Close()
+
+	versions := make(map[int]time.Time)
+	for rows.Next() {
+		var v int
+		var at time.Time
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		versions[v] = at
+	}
+	return versions, rows.Err()
+}
+
+// Status lists every known migration in order, and whether it was applied.
+func (m *Migrator) Status() ([]Status, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+	versions, err := applied(m.ctx, m.client.Pool)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := Status{Migration: mig}
+		if at, ok := versions[mig.Version]; ok {
+			s.AppliedAt = &at
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// Up applies every pending migration up to target (0 = Latest), lowest first,
+// and returns the ones it applied.
+func (m *Migrator) Up(target int) ([]Migration, error) {
+	if target <= 0 {
+		target = m.Latest()
+	}
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for _, mig := range m.migrations {
+		if mig.Version > target {
+			break
+		}
+		ran, err := m.step(mig, true)
+		if err != nil {
+			return done, err
+		}
+		if ran {
+			log.Printf("[Migrator] ⬆️ applied %d %s", mig.Version, mig.Name)
+			done = append(done, mig)
+		}
+	}
+	return done, nil
+}
+
+// Down rolls back every applied migration above target, highest first, and
+// returns the ones it rolled back. It stops with ErrIrreversible at the first
+// migration without a Down.
+func (m *Migrator) Down(target int) ([]Migration, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if mig.Version <= target {
+			break
+		}
+		ran, err := m.step(mig, false)
+		if err != nil {
+			return done, err
+		}
+		if ran {
+			log.Printf("[Migrator] ⬇️ rolled back %d %s", mig.Version, mig.Name)
+			done = append(done, mig)
+		}
+	}
+	return done, nil
+}
+
+// step applies (up) or rolls back one migration in its own transaction and
+// reports whether it had anything to do.
+func (m *Migrator) step(mig Migration, up bool) (bool, error) {
+	ran := false
+	err := m.client.WithTx(m.ctx, func(tx pgx.Tx) error {
+		ran = false
+		if _, err := tx.Exec(m.ctx, `SELECT pg_advisory_xact_lock($1)`, lockKey); err != nil {
+			return err
+		}
+		// Đọc lại sau khi có khóa: process khác có thể vừa chạy xong bước này
+		versions, err := applied(m.ctx, tx)
+		if err != nil {
+			return err
+		}
+		_, isApplied := versions[mig.Version]
+		if isApplied == up {
+			return nil
+		}
+
+		if up {
+			if _, err := tx.Exec(m.ctx, mig.Up); err != nil {
+				return err
+			}
+			_, err = tx.Exec(m.ctx, fmt.Sprintf(`INSERT INTO %s (version, name) VALUES ($1, $2)`, TableName),
+				mig.Version, mig.Name)
+		} else {
+			if mig.Down == "" {
+				return ErrIrreversible
+			}
+			if _, err := tx.Exec(m.ctx, mig.Down); err != nil {
+				return err
+			}
+			_, err = tx.Exec(m.ctx, fmt.Sprintf(`DELETE FROM %s WHERE version = $1`, TableName), mig.Version)
+		}
+		if err != nil {
+			return err
+		}
+		ran = true
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("[Migrator] migration %d %s: %w", mig.Version, mig.Name, err)
+	}
+	return ran, nil
+}
@@ -0,0 +1,151 @@
+package migrations
+
+// All is the schema history, oldest first. A released migration is frozen:
+// change the schema by appending a new one, never by editing an old one.
+// The Columns of the tables in package tables, which the query builder uses
+// as its whitelist, must list the same columns with the same definitions;
+// TestTableColumnsMatchMigrations fails when they drift apart.
+var All = []Migration{
+	{
+		Version: 1,
+		Name:    "initial schema",
+		Up: `
+CREATE TABLE IF NOT EXISTS titles (
+	title_id   UUID PRIMARY KEY,
+	wiki       VARCHAR(255) NOT NULL DEFAULT 'en',
+	name       VARCHAR(255) NOT NULL,
+	depth      INT NOT NULL DEFAULT 0,
+	seed       VARCHAR(255),
+	status     VARCHAR(16) NOT NULL DEFAULT 'ok',
+	created_at TIMESTAMP NOT NULL DEFAULT now(),
+	updated_at TIMESTAMP NOT NULL DEFAULT now(),
+	UNIQUE (wiki, name),
+	CHECK (status IN ('ok', 'missing', 'invalid'))
+);
+
+CREATE TABLE IF NOT EXISTS pairs (
+	pair_id     UUID PRIMARY KEY,
+	title_src   UUID NOT NULL,
+	title_dst   UUID NOT NULL,
+	first_seen  TIMESTAMP NOT NULL DEFAULT now(),
+	last_seen   TIMESTAMP NOT NULL DEFAULT now(),
+	occurrences INT NOT NULL DEFAULT 1,
+	in_lead     BOOLEAN,
+	created_at  TIMESTAMP NOT NULL DEFAULT now(),
+	updated_at  TIMESTAMP NOT NULL DEFAULT now(),
+	FOREIGN KEY (title_src) REFERENCES titles(title_id),
+	FOREIGN KEY (title_dst) REFERENCES titles(title_id),
+	UNIQUE (title_src, title_dst)
+);
+
+CREATE TABLE IF NOT EXISTS aliases (
+	wiki       VARCHAR(255) NOT NULL,
+	alias      VARCHAR(255) NOT NULL,
+	title_id   UUID NOT NULL,
+	kind       VARCHAR(16) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT now(),
+	PRIMARY KEY (wiki, alias),
+	FOREIGN KEY (title_id) REFERENCES titles(title_id) ON DELETE CASCADE,
+	CHECK (kind IN ('normalized', 'redirect'))
+);
+CREATE INDEX IF NOT EXISTS idx_aliases_title ON aliases (title_id);
+
+CREATE TABLE IF NOT EXISTS frontier (
+	wiki        VARCHAR(255) NOT NULL DEFAULT 'en',
+	title       VARCHAR(255) NOT NULL,
+	title_id    UUID,
+	depth       INT NOT NULL DEFAULT 0,
+	seed        VARCHAR(255),
+	state       VARCHAR(16) NOT NULL DEFAULT 'pending',
+	attempts    INT NOT NULL DEFAULT 0,
+	lease_until TIMESTAMP,
+	last_error  TEXT,
+	created_at  TIMESTAMP NOT NULL DEFAULT now(),
+	updated_at  TIMESTAMP NOT NULL DEFAULT now(),
+	PRIMARY KEY (wiki, title),
+	CHECK (state IN ('pending', 'in_flight', 'done', 'failed'))
+);
+CREATE INDEX IF NOT EXISTS idx_frontier_state_depth ON frontier (state, depth, updated_at);
+
+CREATE TABLE IF NOT EXISTS rate_limiter_rules (
+	id          SERIAL PRIMARY KEY,
+	action      VARCHAR(50) NOT NULL,
+	target_type VARCHAR(50) NOT NULL,
+	target      VARCHAR(255),
+	limit_value INT NOT NULL,
+	time_unit   VARCHAR(20) NOT NULL,
+	description TEXT,
+	created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);`,
+		Down: `DROP TABLE IF EXISTS rate_limiter_rules, frontier, aliases, pairs, titles;`,
+	},
+	{
+		// Bảng tạo bởi CreateTable trước khi có migration: CREATE TABLE IF NOT EXISTS
+		// ở bước 1 bỏ qua chúng, bước này thêm những cột / constraint còn thiếu.
+		// Index trên cột mới (idx_titles_seed_depth) cũng tạo ở đây, không ở bước 1:
+		// với bảng titles cũ, cột wiki / seed / depth chưa có khi bước 1 chạy.
+		Version: 2,
+		Name:    "upgrade tables created before migrations",
+		Up: `
+ALTER TABLE titles
+	ADD COLUMN IF NOT EXISTS wiki VARCHAR(255) NOT NULL DEFAULT 'en',
+	ADD COLUMN IF NOT EXISTS depth INT NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS seed VARCHAR(255),
+	ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'ok',
+	DROP CONSTRAINT IF EXISTS titles_name_key;
+DROP INDEX IF EXISTS idx_titles_name;
+CREATE INDEX IF NOT EXISTS idx_titles_seed_depth ON titles (wiki, seed, depth);
+
+-- một cạnh cho mỗi (src, dst) trước khi thêm UNIQUE
+DELETE FROM pairs a USING pairs b
+WHERE a.title_src = b.title_src AND a.title_dst = b.title_dst AND a.pair_id > b.pair_id;
+ALTER TABLE pairs
+	ADD COLUMN IF NOT EXISTS first_seen TIMESTAMP NOT NULL DEFAULT now(),
+	ADD COLUMN IF NOT EXISTS last_seen TIMESTAMP NOT NULL DEFAULT now(),
+	ADD COLUMN IF NOT EXISTS occurrences INT NOT NULL DEFAULT 1,
+	ADD COLUMN IF NOT EXISTS in_lead BOOLEAN;
+DROP INDEX IF EXISTS idx_pairs_src_dst;
+
+ALTER TABLE frontier ADD COLUMN IF NOT EXISTS last_error TEXT, DROP CONSTRAINT IF EXISTS frontier_state_check;
+ALTER TABLE frontier ADD CONSTRAINT frontier_state_check CHECK (state IN ('pending', 'in_flight', 'done', 'failed'));
+
+ALTER TABLE rate_limiter_rules ADD COLUMN IF NOT EXISTS target VARCHAR(255);
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'titles_wiki_name_key' AND conrelid = 'titles'::regclass) THEN
+		ALTER TABLE titles ADD CONSTRAINT titles_wiki_name_key UNIQUE (wiki, name);
+	END IF;
+	IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'titles_status_check' AND conrelid = 'titles'::regclass) THEN
+		ALTER TABLE titles ADD CONSTRAINT titles_status_check CHECK (status IN ('ok', 'missing', 'invalid'));
+	END IF;
+	IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'pairs_title_src_title_dst_key' AND conrelid = 'pairs'::regclass) THEN
+		ALTER TABLE pairs ADD CONSTRAINT pairs_title_src_title_dst_key UNIQUE (title_src, title_dst);
+	END IF;
+END $$;`,
+		// Không có Down: bảng tạo bởi bước 1 cũng có những cột / constraint này, nên
+		// gỡ chúng ở đây sẽ làm hỏng schema của bước 1. Down dừng ở đây với
+		// ErrIrreversible.
+		Down: "",
+	},
+	{
+		// titles.seed / titles.depth chỉ giữ một seed: title tìm thấy từ nhiều seed
+		// cần một dòng cho mỗi seed để "within N hops of X" đúng với mọi seed.
+		Version: 3,
+		Name:    "depth per seed",
+		Up: `
+CREATE TABLE IF NOT EXISTS title_seeds (
+	title_id UUID NOT NULL,
+	seed     VARCHAR(255) NOT NULL,
+	depth    INT NOT NULL,
+	PRIMARY KEY (title_id, seed),
+	FOREIGN KEY (title_id) REFERENCES titles(title_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_title_seeds_seed_depth ON title_seeds (seed, depth);
+INSERT INTO title_seeds (title_id, seed, depth)
+	SELECT title_id, seed, depth FROM titles WHERE seed IS NOT NULL
+	ON CONFLICT DO NOTHING;`,
+		Down: `DROP TABLE IF EXISTS title_seeds;`,
+	},
+}
@@ -1,11 +1,17 @@
 package dbclient
 
 import (
-	"database/sql"
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
+	"time"
+	"wikicrawler/internal/utils/retry"
 
-	_ "github.com/lib/pq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type PostGresConfig struct {
@@ -14,40 +20,90 @@ type PostGresConfig struct {
 	User     string
 	Password string
 	DBname   string
+
+	MaxConns        int           // số kết nối tối đa của pool
+	MinConns        int           // số kết nối luôn giữ sẵn
+	MaxConnLifetime time.Duration // kết nối cũ hơn bị đóng và mở lại, 0 = mặc định của pgx
+	MaxConnIdleTime time.Duration // kết nối rảnh lâu hơn bị đóng, 0 = mặc định của pgx
+	ConnectTimeout  time.Duration // cho mỗi lần mở kết nối, 0 = không giới hạn
+	ConnectAttempts int           // số lần thử kết nối lúc khởi động (DB chưa lên, VD docker compose)
 }
 
 type PostgresClient struct {
-	DB *sql.DB
+	Pool    *pgxpool.Pool
+	TxRetry retry.Policy // WithTx chạy lại transaction bị serialization failure / deadlock theo policy này
 }
 
-func NewPostgresClient(config PostGresConfig) *PostgresClient {
-	psqlInfo := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		config.Host, config.Port, config.User, config.Password, config.DBname,
-	)
+// Querier là phần chung của *pgxpool.Pool và pgx.Tx mà các table dùng để truy vấn
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
 
-	db, err := sql.Open("postgres", psqlInfo)
+// NewPostgresClient mở pool và ping DB, thử lại tối đa config.ConnectAttempts lần
+// (chờ tăng dần) trước khi trả lỗi.
+func NewPostgresClient(ctx context.Context, config PostGresConfig) (*PostgresClient, error) {
+	poolCfg, err := pgxpool.ParseConfig("sslmode=disable")
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(config.Port, 10, 16)
 	if err != nil {
-		log.Fatalf("Không thể mở kết nối DB: %v", err)
+		return nil, fmt.Errorf("[PostgresClient] bad port %q: %w", config.Port, err)
+	}
+	cc := poolCfg.ConnConfig
+	cc.Host, cc.Port, cc.User, cc.Password, cc.Database = config.Host, uint16(port), config.User, config.Password, config.DBname
+	cc.Fallbacks = nil
+	cc.ConnectTimeout = config.ConnectTimeout
+	if config.MaxConns > 0 {
+		poolCfg.MaxConns = int32(config.MaxConns)
+	}
+	poolCfg.MinConns = int32(config.MinConns)
+	if config.MaxConnLifetime > 0 {
+		poolCfg.MaxConnLifetime = config.MaxConnLifetime
+	}
+	if config.MaxConnIdleTime > 0 {
+		poolCfg.MaxConnIdleTime = config.MaxConnIdleTime
 	}
 
-	err = db.Ping()
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
 	if err != nil {
-		log.Fatalf("Không thể ping DB: %v", err)
+		return nil, fmt.Errorf("[PostgresClient] bad pool config: %w", err)
+	}
+
+	connect := retry.Exponential{Base: time.Second, Max: 30 * time.Second, MaxAttempts: max(config.ConnectAttempts, 1)}
+	err = retry.Do(connect, func(attempt int) error {
+		err := pool.Ping(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return retry.Permanent(err)
+		}
+		log.Printf("[PostgresClient] ⚠️ không thể kết nối %s:%s (lần %d): %v", config.Host, config.Port, attempt, err)
+		return err
+	})
+	if err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("[PostgresClient] không thể kết nối DB: %w", err)
 	}
 
 	log.Println("✅ Kết nối PostgreSQL thành công!")
-	return &PostgresClient{DB: db}
+	return &PostgresClient{
+		Pool:    pool,
+		TxRetry: retry.Exponential{Base: 20 * time.Millisecond, Max: time.Second, MaxAttempts: 5, Jitter: 0.5},
+	}, nil
 }
 
 func (pc *PostgresClient) Close() {
-	if pc.DB != nil {
-		pc.DB.Close()
+	if pc.Pool != nil {
+		pc.Pool.Close()
 	}
 }
 
-func (pc *PostgresClient) SearchTable(tb string) bool {
-	if pc.DB == nil {
+func (pc *PostgresClient) SearchTable(ctx context.Context, tb string) bool {
+	if pc.Pool == nil {
 		log.Println("❌ Database connection is not initialized")
 		return false
 	}
@@ -61,7 +117,7 @@ func (pc *PostgresClient) SearchTable(tb string) bool {
 			AND table_name = $1
 		)
 	`
-	err := pc.DB.QueryRow(query, tb).Scan(&exists) // tb (kiểu string) sẽ được gán vào chỗ $1 trong câu SQL.
+	err := pc.Pool.QueryRow(ctx, query, tb).Scan(&exists) // tb (kiểu string) sẽ được gán vào chỗ $1 trong câu SQL.
 	if err != nil {
 		log.Printf("❌ Error checking table existence: %v", err)
 		return false
@@ -69,3 +125,37 @@ func (pc *PostgresClient) SearchTable(tb string) bool {
 
 	return exists
 }
+
+// WithTx chạy fn trong một transaction: commit khi fn trả về nil, rollback khi
+// có lỗi. Transaction bị serialization failure (40001) hoặc deadlock (40P01)
+// được chạy lại từ đầu, nên fn không được có side effect ngoài tx.
+func (pc *PostgresClient) WithTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	return retry.Do(pc.TxRetry, func(attempt int) error {
+		err := pc.runTx(ctx, fn)
+		if err == nil || isRetryableTx(err) {
+			return err
+		}
+		return retry.Permanent(err)
+	})
+}
+
+func (pc *PostgresClient) runTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := pc.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// isRetryableTx cho biết err có phải lỗi mà chạy lại transaction sẽ hết không
+func isRetryableTx(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == "40001" || pgErr.Code == "40P01" // serialization_failure, deadlock_detected
+}
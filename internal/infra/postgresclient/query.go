@@ -0,0 +1,373 @@
+package dbclient
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Cursor là vị trí sau dòng cuối của một trang (keyset pagination): giá trị
+// các cột OrderBy của dòng đó, mã hóa base64 để trả thẳng cho client.
+type Cursor string
+
+// ErrBadCursor is returned for a cursor that was not made by Page with the same OrderBy.
+var ErrBadCursor = errors.New("bad cursor")
+
+// Order is the direction of an OrderBy column.
+type Order string
+
+const (
+	Asc  Order = "ASC"
+	Desc Order = "DESC"
+)
+
+// các toán tử Where được phép; IN nhận một slice, IS NULL / IS NOT NULL bỏ qua value
+var whereOps = []string{"=", "<>", "<", "<=", ">", ">=", "LIKE", "ILIKE", "IN", "IS NULL", "IS NOT NULL"}
+
+type whereCond struct {
+	column string
+	op     string
+	value  interface{}
+}
+
+type orderCol struct {
+	column string
+	order  Order
+}
+
+// Query là một SELECT trên một table. Tên cột chỉ được nhận khi có trong
+// BaseTable.Columns và giá trị luôn đi qua tham số $n, nên không thể chèn SQL.
+// Lỗi (cột lạ, toán tử lạ, cursor hỏng) được giữ lại và trả về khi chạy.
+type Query struct {
+	table  *BaseTable
+	cols   []string
+	wheres []whereCond
+	orders []orderCol
+	limit  int
+	after  Cursor
+	err    error
+}
+
+// HasColumn cho biết column có trong metadata của table hay không
+func (bt *BaseTable) HasColumn(column string) bool {
+	_, ok := bt.Columns[column]
+	return ok
+}
+
+// Select bắt đầu một truy vấn lấy cols (không có = mọi cột)
+func (bt *BaseTable) Select(cols ...string) *Query {
+	q := &Query{table: bt}
+	for _, c := range cols {
+		q.checkColumn(c)
+	}
+	q.cols = cols
+	return q
+}
+
+func (q *Query) checkColumn(column string) {
+	if q.err == nil && !q.table.HasColumn(column) {
+		q.err = fmt.Errorf("❌ %s không có cột %q", q.table.TableName, column)
+	}
+}
+
+// Where thêm điều kiện column op value; các điều kiện nối bằng AND
+func (q *Query) Where(column, op string, value interface{}) *Query {
+	q.checkColumn(column)
+	op = strings.ToUpper(strings.TrimSpace(op))
+	if q.err == nil && !slices.Contains(whereOps, op) {
+		q.err = fmt.Errorf("❌ toán tử %q không được hỗ trợ", op)
+	}
+	q.wheres = append(q.wheres, whereCond{column: column, op: op, value: value})
+	return q
+}
+
+// OrderBy thêm một cột sắp xếp. Để phân trang bằng cursor, các cột OrderBy
+// phải cùng chiều và cột cuối phải là duy nhất (VD: khóa chính).
+func (q *Query) OrderBy(column string, order Order) *Query {
+	q.checkColumn(column)
+	if q.err == nil && order != Asc && order != Desc {
+		q.err = fmt.Errorf("❌ chiều sắp xếp %q không hợp lệ", order)
+	}
+	q.orders = append(q.orders, orderCol{column: column, order: order})
+	return q
+}
+
+// Limit giới hạn số dòng; n <= 0 là không giới hạn
+func (q *Query) Limit(n int) *Query {
+	q.limit = n
+	return q
+}
+
+// After bắt đầu sau dòng mà cursor trỏ tới; cursor rỗng là từ đầu
+func (q *Query) After(cursor Cursor) *Query {
+	q.after = cursor
+	return q
+}
+
+// build trả về câu SQL và các tham số
+func (q *Query) build() (string, []interface{}, error) {
+	if q.err != nil {
+		return "", nil, q.err
+	}
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	cols := "*"
+	if len(q.cols) > 0 {
+		selected := slices.Clone(q.cols)
+		for _, o := range q.orders {
+			if !slices.Contains(selected, o.column) {
+				selected = append(selected, o.column) // Page cần giá trị các cột này để tạo cursor
+			}
+		}
+		cols = strings.Join(selected, ", ")
+	}
+
+	var conds []string
+	for _, w := range q.wheres {
+		switch w.op {
+		case "IS NULL", "IS NOT NULL":
+			conds = append(conds, fmt.Sprintf("%s %s", w.column, w.op))
+		case "IN":
+			cast := ""
+			if strings.HasPrefix(strings.ToUpper(q.table.Columns[w.column]), "UUID") {
+				cast = "::text[]::uuid[]" // pgx không mã hóa []string thành uuid[] dạng binary
+			}
+			conds = append(conds, fmt.Sprintf("%s = ANY(%s%s)", w.column, arg(w.value), cast))
+		default:
+			conds = append(conds, fmt.Sprintf("%s %s %s", w.column, w.op, arg(w.value)))
+		}
+	}
+
+	if q.after != "" {
+		values, err := decodeCursor(q.after, len(q.orders))
+		if err != nil {
+			return "", nil, err
+		}
+		for i, o := range q.orders {
+			if values[i], err = cursorValue(q.table.Columns[o.column], values[i]); err != nil {
+				return "", nil, err
+			}
+		}
+		for _, o := range q.orders[1:] {
+			if o.order != q.orders[0].order {
+				return "", nil, fmt.Errorf("❌ cursor cần các cột OrderBy cùng chiều")
+			}
+		}
+		op := ">"
+		if q.orders[0].order == Desc {
+			op = "<"
+		}
+		keys := make([]string, len(q.orders))
+		marks := make([]string, len(q.orders))
+		for i, o := range q.orders {
+			keys[i], marks[i] = o.column, arg(values[i])
+		}
+		// so sánh theo hàng: (a, b) > ($1, $2) dùng được index trên (a, b)
+		conds = append(conds, fmt.Sprintf("(%s) %s (%s)", strings.Join(keys, ", "), op, strings.Join(marks, ", ")))
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM %s`, cols, q.table.TableName)
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	if len(q.orders) > 0 {
+		parts := make([]string, len(q.orders))
+		for i, o := range q.orders {
+			parts[i] = fmt.Sprintf("%s %s", o.column, o.order)
+		}
+		query += " ORDER BY " + strings.Join(parts, ", ")
+	}
+	if q.limit > 0 {
+		query += " LIMIT " + arg(q.limit)
+	}
+	return query, args, nil
+}
+
+// Rows chạy truy vấn và trả từng dòng (cột -> giá trị) khi đọc tới, không giữ
+// cả kết quả trong bộ nhớ. Dừng vòng lặp sớm sẽ đóng kết quả.
+func (q *Query) Rows() iter.Seq2[map[string]interface{}, error] {
+	return func(yield func(map[string]interface{}, error) bool) {
+		query, args, err := q.build()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		rows, err := q.table.DB().Query(q.table.Context(), query, args...)
+		if err != nil {
+			yield(nil, fmt.Errorf("❌ lỗi query %s: %w", q.table.TableName, err))
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			row, err := rowMap(rows)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(row, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// rowMap đọc dòng hiện tại của rows thành map cột -> giá trị; uuid được trả về dạng chuỗi
+func rowMap(rows pgx.Rows) (map[string]interface{}, error) {
+	values, err := rows.Values()
+	if err != nil {
+		return nil, err
+	}
+	fields := rows.FieldDescriptions()
+	row := make(map[string]interface{}, len(fields))
+	for i, f := range fields {
+		v := values[i]
+		if b, ok := v.([16]byte); ok && f.DataTypeOID == pgtype.UUIDOID {
+			v = uuid.UUID(b).String()
+		}
+		row[f.Name] = v
+	}
+	return row, nil
+}
+
+// All gom mọi dòng của Rows vào một slice
+func (q *Query) All() ([]map[string]interface{}, error) {
+	var out []map[string]interface{}
+	for row, err := range q.Rows() {
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, row)
+	}
+	return out, nil
+}
+
+// Page trả về một trang (cần Limit và OrderBy) và cursor của trang sau;
+// cursor rỗng khi không còn dòng nào sau trang này. Page đọc thêm một dòng để
+// biết điều đó, nên trang cuối có đúng Limit dòng cũng trả về cursor rỗng.
+func (q *Query) Page() ([]map[string]interface{}, Cursor, error) {
+	if q.err == nil && (q.limit <= 0 || len(q.orders) == 0) {
+		q.err = fmt.Errorf("❌ Page cần Limit và OrderBy")
+	}
+	probe := *q
+	probe.limit = q.limit + 1
+	rows, err := probe.All()
+	if err != nil || len(rows) <= q.limit {
+		return rows, "", err
+	}
+	rows = rows[:q.limit]
+	last := rows[len(rows)-1]
+	values := make([]interface{}, len(q.orders))
+	for i, o := range q.orders {
+		values[i] = last[o.column]
+	}
+	next, err := encodeCursor(values)
+	return rows, next, err
+}
+
+func encodeCursor(values []interface{}) (Cursor, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return Cursor(base64.RawURLEncoding.EncodeToString(data)), nil
+}
+
+func decodeCursor(c Cursor, n int) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(string(c))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadCursor, err)
+	}
+	// UseNumber: số nguyên lớn không bị đổi thành float64
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var values []interface{}
+	if err := dec.Decode(&values); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadCursor, err)
+	}
+	if len(values) != n {
+		return nil, fmt.Errorf("%w: %d value(s) for %d OrderBy column(s)", ErrBadCursor, len(values), n)
+	}
+	for i, v := range values {
+		if num, ok := v.(json.Number); ok {
+			if n, err := num.Int64(); err == nil {
+				values[i] = n
+			} else if f, err := num.Float64(); err == nil {
+				values[i] = f
+			}
+		}
+	}
+	return values, nil
+}
+
+// cursorValue đưa giá trị đọc từ JSON về kiểu của cột: thời gian được mã hóa
+// thành chuỗi RFC 3339, so sánh chuỗi đó với cột TIMESTAMP sẽ sai kiểu.
+func cursorValue(colType string, v interface{}) (interface{}, error) {
+	s, ok := v.(string)
+	typ := strings.ToUpper(colType)
+	if !ok || !(strings.HasPrefix(typ, "TIMESTAMP") || strings.HasPrefix(typ, "DATE")) {
+		return v, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadCursor, err)
+	}
+	return t, nil
+}
+
+// Find chạy q với các cột của T và trả từng dòng dưới dạng T, không buffer
+func (r *Repository[T]) Find(q *Query) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		cols := make([]string, len(r.fields))
+		for i, f := range r.fields {
+			cols[i] = f.column
+		}
+		for _, o := range q.orders {
+			if !slices.Contains(cols, o.column) {
+				yield(zero, fmt.Errorf("❌ cột OrderBy %q không có trong %T", o.column, zero))
+				return
+			}
+		}
+		typed := *q
+		typed.cols = cols
+		query, args, err := typed.build()
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		rows, err := q.table.DB().Query(q.table.Context(), query, args...)
+		if err != nil {
+			yield(zero, fmt.Errorf("❌ lỗi query %s: %w", q.table.TableName, err))
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			v, err := r.scan(rows)
+			if !yield(v, err) || err != nil {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package dbclient
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Repository đọc / ghi một table qua struct T thay vì map[string]interface{}.
+// Mỗi field được lưu có tag `db:"column"`, kèm các option sau dấu phẩy:
+//
+//	pk       khóa chính (Get/Delete theo pk, conflict mặc định của Upsert)
+//	default  bỏ qua khi Insert/Upsert nếu field đang là zero value, để DB tự điền
+//
+// Field không có tag (hoặc tag "-") bị bỏ qua. Cột NULL được đọc thành zero value.
+type Repository[T any] struct {
+	BaseTable
+	fields []repoField
+}
+
+type repoField struct {
+	column  string
+	index   []int
+	pk      bool
+	dbFill  bool // option default
+	colType reflect.Type
+}
+
+// NewRepository đọc tag của T; panic nếu T không phải struct, không có cột nào,
+// có tag sai (option lạ, tên cột rỗng hoặc trùng, cột không có trong
+// table.Columns) hoặc tag trên field không export, vì đó là lỗi lập trình chứ
+// không phải lỗi lúc chạy.
+func NewRepository[T any](table BaseTable) Repository[T] {
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("dbclient: Repository of %s, want a struct", typ))
+	}
+	var fields []repoField
+	seen := make(map[string]bool)
+	for _, f := range reflect.VisibleFields(typ) {
+		tag := f.Tag.Get("db")
+		if tag == "" || tag == "-" || f.Anonymous {
+			continue
+		}
+		if !f.IsExported() {
+			panic(fmt.Sprintf("dbclient: %s.%s has a `db` tag but is not exported", typ, f.Name))
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		switch {
+		case name == "":
+			panic(fmt.Sprintf("dbclient: %s.%s has no column name in its `db` tag", typ, f.Name))
+		case seen[name]:
+			panic(fmt.Sprintf("dbclient: %s has two fields for column %q", typ, name))
+		case table.Columns != nil && !table.HasColumn(name):
+			panic(fmt.Sprintf("dbclient: %s.%s: %s has no column %q", typ, f.Name, table.TableName, name))
+		}
+		seen[name] = true
+		rf := repoField{column: name, index: f.Index, colType: f.Type}
+		for _, o := range strings.Split(opts, ",") {
+			switch o {
+			case "":
+			case "pk":
+				rf.pk = true
+			case "default":
+				rf.dbFill = true
+			default:
+				panic(fmt.Sprintf("dbclient: %s.%s has unknown `db` option %q", typ, f.Name, o))
+			}
+		}
+		fields = append(fields, rf)
+	}
+	if len(fields) == 0 {
+		panic(fmt.Sprintf("dbclient: %s has no `db` tags", typ))
+	}
+	return Repository[T]{BaseTable: table, fields: fields}
+}
+
+// InTx trả về bản sao của repository chạy mọi truy vấn trong tx
+func (r Repository[T]) InTx(ctx context.Context, tx pgx.Tx) Repository[T] {
+	r.BaseTable = r.BaseTable.InTx(ctx, tx)
+	return r
+}
+
+func (r *Repository[T]) columnList() string {
+	cols := make([]string, len(r.fields))
+	for i, f := range r.fields {
+		cols[i] = f.column
+	}
+	return strings.Join(cols, ", ")
+}
+
+func (r *Repository[T]) field(column string) (repoField, bool) {
+	for _, f := range r.fields {
+		if f.column == column {
+			return f, true
+		}
+	}
+	return repoField{}, false
+}
+
+func (r *Repository[T]) pkColumns() []string {
+	var cols []string
+	for _, f := range r.fields {
+		if f.pk {
+			cols = append(cols, f.column)
+		}
+	}
+	return cols
+}
+
+// values trả về các cột và giá trị sẽ ghi cho v
+func (r *Repository[T]) values(v *T) ([]string, []interface{}) {
+	rv := reflect.ValueOf(v).Elem()
+	var cols []string
+	var vals []interface{}
+	for _, f := range r.fields {
+		fv := rv.FieldByIndex(f.index)
+		if f.dbFill && fv.IsZero() {
+			continue
+		}
+		cols = append(cols, f.column)
+		vals = append(vals, fv.Interface())
+	}
+	return cols, vals
+}
+
+// scan đọc một dòng có đúng các cột của r.columnList() vào T
+func (r *Repository[T]) scan(row interface{ Scan(...interface{}) error }) (T, error) {
+	var v T
+	rv := reflect.ValueOf(&v).Elem()
+	// Scan vào **X: pgx để nil khi cột NULL, field giữ zero value
+	ptrs := make([]reflect.Value, len(r.fields))
+	dests := make([]interface{}, len(r.fields))
+	for i, f := range r.fields {
+		ptrs[i] = reflect.New(reflect.PointerTo(f.colType))
+		dests[i] = ptrs[i].Interface()
+	}
+	if err := row.Scan(dests...); err != nil {
+		return v, err
+	}
+	for i, f := range r.fields {
+		if p := ptrs[i].Elem(); !p.IsNil() {
+			rv.FieldByIndex(f.index).Set(p.Elem())
+		}
+	}
+	return v, nil
+}
+
+func placeholders(n int) string {
+	ph := make([]string, n)
+	for i := range ph {
+		ph[i] = fmt.Sprintf("$%d", i+1)
+	}
+	return strings.Join(ph, ", ")
+}
+
+// Insert thêm v, bỏ qua nếu trùng khóa; trả về true nếu dòng thực sự được thêm
+func (r *Repository[T]) Insert(v T) (bool, error) {
+	query, vals := r.insertSQL(&v)
+	tag, err := r.DB().Exec(r.Context(), query, vals...)
+	if err != nil {
+		return false, fmt.Errorf("❌ lỗi insert vào %s: %w", r.TableName, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *Repository[T]) insertSQL(v *T) (string, []interface{}) {
+	cols, vals := r.values(v)
+	query := fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s) ON CONFLICT DO NOTHING`,
+		r.TableName, strings.Join(cols, ", "), placeholders(len(cols)))
+	return query, vals
+}
+
+// Upsert thêm v, hoặc cập nhật dòng trùng conflict (mặc định là khóa chính) bằng
+// các cột còn lại của v, và trả về dòng sau khi ghi
+func (r *Repository[T]) Upsert(v T, conflict ...string) (T, error) {
+	var zero T
+	query, vals, err := r.upsertSQL(&v, conflict)
+	if err != nil {
+		return zero, err
+	}
+	out, err := r.scan(r.DB().QueryRow(r.Context(), query, vals...))
+	if err != nil {
+		return zero, fmt.Errorf("❌ lỗi upsert vào %s: %w", r.TableName, err)
+	}
+	return out, nil
+}
+
+func (r *Repository[T]) upsertSQL(v *T, conflict []string) (string, []interface{}, error) {
+	if len(conflict) == 0 {
+		conflict = r.pkColumns()
+	}
+	if len(conflict) == 0 {
+		return "", nil, fmt.Errorf("❌ upsert vào %s cần cột conflict: %T không có field pk", r.TableName, *v)
+	}
+	for _, c := range conflict {
+		if _, ok := r.field(c); !ok {
+			return "", nil, fmt.Errorf("❌ %s không có cột %q", r.TableName, c)
+		}
+	}
+
+	cols, vals := r.values(v)
+	var sets []string
+	for _, c := range cols {
+		if f, _ := r.field(c); !f.pk && !slices.Contains(conflict, c) {
+			sets = append(sets, fmt.Sprintf("%s = EXCLUDED.%s", c, c))
+		}
+	}
+	if len(sets) == 0 {
+		// DO NOTHING không trả về dòng đã có; ghi lại chính khóa để RETURNING luôn có kết quả
+		sets = append(sets, fmt.Sprintf("%s = EXCLUDED.%s", conflict[0], conflict[0]))
+	}
+	query := fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO UPDATE SET %s RETURNING %s`,
+		r.TableName, strings.Join(cols, ", "), placeholders(len(cols)), strings.Join(conflict, ", "),
+		strings.Join(sets, ", "), r.columnList())
+	return query, vals, nil
+}
+
+// Get trả về dòng đầu tiên có column = value; lỗi bọc pgx.ErrNoRows nếu không có
+func (r *Repository[T]) Get(column string, value interface{}) (T, error) {
+	var zero T
+	if _, ok := r.field(column); !ok {
+		return zero, fmt.Errorf("❌ %s không có cột %q", r.TableName, column)
+	}
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE %s = $1 LIMIT 1`, r.columnList(), r.TableName, column)
+	v, err := r.scan(r.DB().QueryRow(r.Context(), query, value))
+	if err != nil {
+		return zero, fmt.Errorf("❌ không tìm thấy record có %s = %v trong %s: %w", column, value, r.TableName, err)
+	}
+	return v, nil
+}
+
+// List trả về mọi dòng của table; chỉ dùng cho table nhỏ
+func (r *Repository[T]) List() ([]T, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s`, r.columnList(), r.TableName)
+	rows, err := r.DB().Query(r.Context(), query)
+	if err != nil {
+		return nil, fmt.Errorf("❌ lỗi query %s: %w", r.TableName, err)
+	}
+	defer rows.Close()
+
+	var out []T
+	for rows.Next() {
+		v, err := r.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
+
+// Delete xóa các dòng có column = value và trả về số dòng đã xóa
+func (r *Repository[T]) Delete(column string, value interface{}) (int64, error) {
+	if _, ok := r.field(column); !ok {
+		return 0, fmt.Errorf("❌ %s không có cột %q", r.TableName, column)
+	}
+	query := fmt.Sprintf(`DELETE FROM %s WHERE %s = $1`, r.TableName, column)
+	tag, err := r.DB().Exec(r.Context(), query, value)
+	if err != nil {
+		return 0, fmt.Errorf("❌ lỗi xóa khỏi %s: %w", r.TableName, err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,144 @@
+package dbclient
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testRow struct {
+	ID        string    `db:"title_id,pk"`
+	Name      string    `db:"name"`
+	Note      string    // no tag: not stored
+	Skipped   int       `db:"-"`
+	CreatedAt time.Time `db:"created_at,default"`
+}
+
+func testRepo[T any]() Repository[T] {
+	return NewRepository[T](BaseTable{TableName: "titles", Columns: testTable().Columns})
+}
+
+func TestNewRepositoryRejectsBadTags(t *testing.T) {
+	type unknownOption struct {
+		ID string `db:"title_id,primary"`
+	}
+	type noName struct {
+		ID string `db:",pk"`
+	}
+	type duplicate struct {
+		ID    string `db:"title_id,pk"`
+		Other string `db:"title_id"`
+	}
+	type unknownColumn struct {
+		ID string `db:"uuid,pk"`
+	}
+	type unexported struct {
+		ID   string `db:"title_id,pk"`
+		name string `db:"name"`
+	}
+	type untagged struct{ ID string }
+
+	for name, newRepo := range map[string]func(){
+		"not a struct":   func() { testRepo[string]() },
+		"unknown option": func() { testRepo[unknownOption]() },
+		"no column name": func() { testRepo[noName]() },
+		"same column":    func() { testRepo[duplicate]() },
+		"unknown column": func() { testRepo[unknownColumn]() },
+		"unexported":     func() { testRepo[unexported]() },
+		"no tags":        func() { testRepo[untagged]() },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("NewRepository did not panic")
+				}
+			}()
+			newRepo()
+		})
+	}
+}
+
+func TestRepositoryFields(t *testing.T) {
+	r := testRepo[testRow]()
+	if got := r.columnList(); got != "title_id, name, created_at" {
+		t.Errorf("columns = %q", got)
+	}
+	if got := r.pkColumns(); !reflect.DeepEqual(got, []string{"title_id"}) {
+		t.Errorf("pk = %v", got)
+	}
+}
+
+func TestRepositoryInsertSQL(t *testing.T) {
+	r := testRepo[testRow]()
+	created := time.Date(2024, 5, 17, 0, 0, 0, 0, time.UTC)
+
+	query, vals := r.insertSQL(&testRow{ID: "id", Name: "A", Note: "x", Skipped: 1})
+	if want := "INSERT INTO titles (title_id, name) VALUES ($1, $2) ON CONFLICT DO NOTHING"; query != want {
+		t.Errorf("zero default column:\n got %s\nwant %s", query, want)
+	}
+	if !reflect.DeepEqual(vals, []interface{}{"id", "A"}) {
+		t.Errorf("vals = %#v", vals)
+	}
+
+	query, vals = r.insertSQL(&testRow{ID: "id", Name: "A", CreatedAt: created})
+	if want := "INSERT INTO titles (title_id, name, created_at) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING"; query != want {
+		t.Errorf("set default column:\n got %s\nwant %s", query, want)
+	}
+	if len(vals) != 3 || vals[2] != created {
+		t.Errorf("vals = %#v", vals)
+	}
+}
+
+func TestRepositoryUpsertSQL(t *testing.T) {
+	r := testRepo[testRow]()
+	for _, c := range []struct {
+		name     string
+		conflict []string
+		want     string
+		wantErr  string
+	}{
+		{
+			name: "on the primary key",
+			want: "INSERT INTO titles (title_id, name) VALUES ($1, $2) ON CONFLICT (title_id) " +
+				"DO UPDATE SET name = EXCLUDED.name RETURNING title_id, name, created_at",
+		},
+		{
+			name:     "on another column",
+			conflict: []string{"name"},
+			want: "INSERT INTO titles (title_id, name) VALUES ($1, $2) ON CONFLICT (name) " +
+				"DO UPDATE SET name = EXCLUDED.name RETURNING title_id, name, created_at",
+		},
+		{
+			name:     "unknown conflict column",
+			conflict: []string{"name; --"},
+			wantErr:  "không có cột",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			query, _, err := r.upsertSQL(&testRow{ID: "id", Name: "A"}, c.conflict)
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Fatalf("err = %v, want %q", err, c.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if query != c.want {
+				t.Errorf("\n got %s\nwant %s", query, c.want)
+			}
+		})
+	}
+}
+
+func TestRepositoryUpsertNeedsAKey(t *testing.T) {
+	type noPK struct {
+		Name string `db:"name"`
+	}
+	r := testRepo[noPK]()
+	if _, _, err := r.upsertSQL(&noPK{Name: "A"}, nil); err == nil {
+		t.Error("upsert without a pk or conflict column built a query")
+	}
+}
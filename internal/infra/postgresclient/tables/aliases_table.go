@@ -0,0 +1,88 @@
+package tables
+
+import (
+	"context"
+	"fmt"
+	dbclient "wikicrawler/internal/infra/postgresclient"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const AliasesTableName = "aliases"
+
+const (
+	AliasNormalized = "normalized" // e.g. "donald_trump" -> "Donald trump"
+	AliasRedirect   = "redirect"   // e.g. "Donald J. Trump" -> "Donald Trump"
+)
+
+// AliasesTable kế thừa BaseTable, lưu mọi tên (redirect / normalized) trỏ về title chuẩn
+type AliasesTable struct {
+	dbclient.BaseTable
+}
+
+// NewAliasesTable khởi tạo table aliases
+func NewAliasesTable(client *dbclient.PostgresClient) *AliasesTable {
+	return &AliasesTable{
+		BaseTable: dbclient.BaseTable{
+			Client:    client,
+			TableName: AliasesTableName,
+			Columns: map[string]string{
+				"wiki":       "VARCHAR(255) NOT NULL",
+				"alias":      "VARCHAR(255) NOT NULL",
+				"title_id":   "UUID NOT NULL", // title chuẩn
+				"kind":       "VARCHAR(16) NOT NULL",
+				"created_at": "TIMESTAMP NOT NULL DEFAULT now()",
+			},
+			Constraints: []string{
+				"PRIMARY KEY (wiki, alias)",
+				"FOREIGN KEY (title_id) REFERENCES titles(title_id) ON DELETE CASCADE",
+				"CHECK (kind IN ('normalized', 'redirect'))",
+				"CREATE INDEX IF NOT EXISTS idx_aliases_title ON aliases (title_id)",
+			},
+		},
+	}
+}
+
+// Tx trả về bản sao của table chạy trong tx
+func (a *AliasesTable) Tx(ctx context.Context, tx pgx.Tx) *AliasesTable {
+	return &AliasesTable{BaseTable: a.BaseTable.InTx(ctx, tx)}
+}
+
+// ResolveMany trả về map alias -> title_id cho các tên đã biết là alias
+func (a *AliasesTable) ResolveMany(wiki string, names []string) (map[string]string, error) {
+	query := fmt.Sprintf(`SELECT alias, title_id FROM %s WHERE wiki = $1 AND alias = ANY($2)`, a.TableName)
+	rows, err := a.DB().Query(a.Context(), query, wiki, names)
+	if err != nil {
+		return nil, fmt.Errorf("❌ lỗi query %s: %w", a.TableName, err)
+	}
+	defer rows.Close()
+
+	ids := make(map[string]string)
+	for rows.Next() {
+		var alias, id string
+		if err := rows.Scan(&alias, &id); err != nil {
+			return nil, err
+		}
+		ids[alias] = id
+	}
+	return ids, rows.Err()
+}
+
+// Upsert ghi alias trỏ về titleID, ghi đè nếu alias đã trỏ về title khác
+func (a *AliasesTable) Upsert(wiki, alias, titleID, kind string) error {
+	query := fmt.Sprintf(`INSERT INTO %s (wiki, alias, title_id, kind) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (wiki, alias) DO UPDATE SET title_id = EXCLUDED.title_id, kind = EXCLUDED.kind`, a.TableName)
+	if _, err := a.DB().Exec(a.Context(), query, wiki, alias, titleID, kind); err != nil {
+		return fmt.Errorf("❌ lỗi ghi alias '%s' (%s): %w", alias, wiki, err)
+	}
+	return nil
+}
+
+// Repoint chuyển mọi alias của title fromID sang title toID (dùng khi gộp alias)
+func (a *AliasesTable) Repoint(fromID, toID string) error {
+	query := fmt.Sprintf(`UPDATE %s SET title_id = $2 WHERE title_id = $1`, a.TableName)
+	if _, err := a.DB().Exec(a.Context(), query, fromID, toID); err != nil {
+		return fmt.Errorf("❌ lỗi chuyển alias %s → %s: %w", fromID, toID, err)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package tables
+
+import (
+	"fmt"
+	"strings"
+)
+
+// bulkRows giới hạn số dòng của một câu INSERT nhiều dòng (Postgres nhận tối đa 65535 tham số)
+const bulkRows = 1000
+
+// valuesList trả về "($1, $2), ($3, $4)" cho rows dòng, mỗi dòng cols tham số
+func valuesList(rows, cols int) string {
+	var b strings.Builder
+	for r := 0; r < rows; r++ {
+		if r > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('(')
+		for c := 0; c < cols; c++ {
+			if c > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "$%d", r*cols+c+1)
+		}
+		b.WriteByte(')')
+	}
+	return b.String()
+}
@@ -0,0 +1,127 @@
+package tables
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	dbclient "wikicrawler/internal/infra/postgresclient"
+	"wikicrawler/internal/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const FrontierTableName = "frontier"
+
+// FrontierTable kế thừa BaseTable, lưu hàng đợi crawl (pending / in_flight / done / failed)
+type FrontierTable struct {
+	dbclient.BaseTable
+}
+
+// NewFrontierTable khởi tạo table frontier
+func NewFrontierTable(client *dbclient.PostgresClient) *FrontierTable {
+	return &FrontierTable{
+		BaseTable: dbclient.BaseTable{
+			Client:    client,
+			TableName: FrontierTableName,
+			Columns: map[string]string{
+				"wiki":        "VARCHAR(255) NOT NULL DEFAULT 'en'",
+				"title":       "VARCHAR(255) NOT NULL",
+				"title_id":    "UUID", // NULL cho seed chưa có trong titles
+				"depth":       "INT NOT NULL DEFAULT 0",
+				"seed":        "VARCHAR(255)",
+				"state":       "VARCHAR(16) NOT NULL DEFAULT 'pending'",
+				"attempts":    "INT NOT NULL DEFAULT 0",
+				"lease_until": "TIMESTAMP",
+				"last_error":  "TEXT", // why a failed title gave up
+				"created_at":  "TIMESTAMP NOT NULL DEFAULT now()",
+				"updated_at":  "TIMESTAMP NOT NULL DEFAULT now()",
+			},
+			Constraints: []string{
+				"PRIMARY KEY (wiki, title)",
+				"CHECK (state IN ('pending', 'in_flight', 'done', 'failed'))",
+				"CREATE INDEX IF NOT EXISTS idx_frontier_state_depth ON frontier (state, depth, updated_at)",
+			},
+		},
+	}
+}
+
+// Tx trả về bản sao của table chạy trong tx
+func (t *FrontierTable) Tx(ctx context.Context, tx pgx.Tx) *FrontierTable {
+	return &FrontierTable{BaseTable: t.BaseTable.InTx(ctx, tx)}
+}
+
+// InsertMany thêm các title chưa có bằng INSERT nhiều dòng và trả về số dòng
+// được thêm. Các dòng được sắp theo (wiki, title) để mọi transaction khóa
+// theo cùng một thứ tự.
+func (t *FrontierTable) InsertMany(items []model.TitleQuery) (int, error) {
+	rows := dedupeItems(items)
+	added := 0
+	for start := 0; start < len(rows); start += bulkRows {
+		chunk := rows[start:min(start+bulkRows, len(rows))]
+		args := make([]interface{}, 0, 5*len(chunk))
+		for _, it := range chunk {
+			var id *string // NULL cho seed chưa có trong titles
+			if it.ID != "" {
+				id = &it.ID
+			}
+			args = append(args, it.Wiki, it.Title, id, it.Depth, it.Seed)
+		}
+		query := fmt.Sprintf(`INSERT INTO %s (wiki, title, title_id, depth, seed) VALUES %s ON CONFLICT DO NOTHING`,
+			t.TableName, valuesList(len(chunk), 5))
+		tag, err := t.DB().Exec(t.Context(), query, args...)
+		if err != nil {
+			return added, fmt.Errorf("❌ lỗi insert vào %s: %w", t.TableName, err)
+		}
+		added += int(tag.RowsAffected())
+	}
+	return added, nil
+}
+
+// Requeue thêm các title chưa có và hạ depth (cùng seed) của các title còn
+// pending khi depth mới nhỏ hơn; title in_flight / done / failed giữ nguyên.
+// Trả về số dòng được thêm hoặc cập nhật.
+func (t *FrontierTable) Requeue(items []model.TitleQuery) (int, error) {
+	rows := dedupeItems(items)
+	n := 0
+	for start := 0; start < len(rows); start += bulkRows {
+		chunk := rows[start:min(start+bulkRows, len(rows))]
+		args := make([]interface{}, 0, 5*len(chunk))
+		for _, it := range chunk {
+			var id *string
+			if it.ID != "" {
+				id = &it.ID
+			}
+			args = append(args, it.Wiki, it.Title, id, it.Depth, it.Seed)
+		}
+		query := fmt.Sprintf(`INSERT INTO %[1]s (wiki, title, title_id, depth, seed) VALUES %[2]s
+			ON CONFLICT (wiki, title) DO UPDATE SET depth = EXCLUDED.depth, seed = EXCLUDED.seed, updated_at = now()
+			WHERE %[1]s.state = 'pending' AND %[1]s.depth > EXCLUDED.depth`, t.TableName, valuesList(len(chunk), 5))
+		tag, err := t.DB().Exec(t.Context(), query, args...)
+		if err != nil {
+			return n, fmt.Errorf("❌ lỗi requeue vào %s: %w", t.TableName, err)
+		}
+		n += int(tag.RowsAffected())
+	}
+	return n, nil
+}
+
+// dedupeItems giữ một dòng cho mỗi (wiki, title), dòng có depth nhỏ nhất, đã sắp xếp
+func dedupeItems(items []model.TitleQuery) []model.TitleQuery {
+	byKey := make(map[string]model.TitleQuery, len(items))
+	for _, it := range items {
+		if prev, ok := byKey[it.Key()]; !ok || it.Depth < prev.Depth {
+			byKey[it.Key()] = it
+		}
+	}
+	rows := make([]model.TitleQuery, 0, len(byKey))
+	for _, it := range byKey {
+		rows = append(rows, it)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Wiki != rows[j].Wiki {
+			return rows[i].Wiki < rows[j].Wiki
+		}
+		return rows[i].Title < rows[j].Title
+	})
+	return rows
+}
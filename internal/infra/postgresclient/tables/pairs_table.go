@@ -1,33 +1,180 @@
 package tables
 
 import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
 	dbclient "wikicrawler/internal/infra/postgresclient"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
-// PairsTable kế thừa BaseTable
+const PairsTableName = "pairs"
+
+// Pair là một cạnh title_src -> title_dst của bảng pairs
+type Pair struct {
+	ID          string    `db:"pair_id,pk"`
+	Src         string    `db:"title_src"`
+	Dst         string    `db:"title_dst"`
+	FirstSeen   time.Time `db:"first_seen,default"`
+	LastSeen    time.Time `db:"last_seen,default"`
+	Occurrences int       `db:"occurrences,default"`
+	InLead      *bool     `db:"in_lead"` // luôn nil: chưa có gì ghi cột này, xem PairsTable
+	CreatedAt   time.Time `db:"created_at,default"`
+	UpdatedAt   time.Time `db:"updated_at,default"`
+}
+
+// PairsTable kế thừa Repository[Pair].
+//
+// Cột in_lead hiện luôn NULL: prop=links không cho biết link nằm ở đâu trong
+// trang. Muốn điền thì phải gọi thêm action=parse&prop=links&section=0 cho mỗi
+// trang (các link của đoạn mở đầu), tức gấp đôi số request.
 type PairsTable struct {
-	dbclient.BaseTable
+	dbclient.Repository[Pair]
 }
 
 // NewPairsTable khởi tạo table pairs
 func NewPairsTable(client *dbclient.PostgresClient) *PairsTable {
 	return &PairsTable{
-		BaseTable: dbclient.BaseTable{
+		Repository: dbclient.NewRepository[Pair](dbclient.BaseTable{
 			Client:    client,
-			TableName: "pairs",
+			TableName: PairsTableName,
 			Columns: map[string]string{
-				"pair_id":    "UUID PRIMARY KEY",
-				"title_src":  "UUID NOT NULL",
-				"title_dst":  "UUID NOT NULL",
-				"created_at": "TIMESTAMP NOT NULL DEFAULT now()",
-				"updated_at": "TIMESTAMP NOT NULL DEFAULT now()",
+				"pair_id":     "UUID PRIMARY KEY",
+				"title_src":   "UUID NOT NULL",
+				"title_dst":   "UUID NOT NULL",
+				"first_seen":  "TIMESTAMP NOT NULL DEFAULT now()",
+				"last_seen":   "TIMESTAMP NOT NULL DEFAULT now()",
+				"occurrences": "INT NOT NULL DEFAULT 1", // số lần crawl thấy cạnh này
+				"in_lead":     "BOOLEAN",                // link nằm ở đoạn mở đầu; luôn NULL, xem PairsTable
+				"created_at":  "TIMESTAMP NOT NULL DEFAULT now()",
+				"updated_at":  "TIMESTAMP NOT NULL DEFAULT now()",
 			},
 			Constraints: []string{
 				"FOREIGN KEY (title_src) REFERENCES titles(title_id)",
 				"FOREIGN KEY (title_dst) REFERENCES titles(title_id)",
-				// ✅ Fixed index table name and columns
-				"CREATE INDEX IF NOT EXISTS idx_pairs_src_dst ON pairs (title_src, title_dst)",
+				"UNIQUE (title_src, title_dst)", // cũng là index cho GetNeighbors
 			},
-		},
+		}),
+	}
+}
+
+// Tx trả về bản sao của table chạy trong tx
+func (p *PairsTable) Tx(ctx context.Context, tx pgx.Tx) *PairsTable {
+	return &PairsTable{Repository: p.Repository.InTx(ctx, tx)}
+}
+
+// InsertMany ghi các cạnh src -> dst bằng INSERT nhiều dòng. Cạnh đã có
+// chỉ được cập nhật last_seen và occurrences. Trả về số cạnh mới.
+func (p *PairsTable) InsertMany(src string, dsts []string) (int64, error) {
+	// Một câu INSERT ... ON CONFLICT DO UPDATE không được chạm cùng một dòng hai lần
+	// (hai link alias cùng trỏ về một title), và thứ tự cố định tránh deadlock
+	dsts = append([]string(nil), dsts...)
+	sort.Strings(dsts)
+	dsts = slices.Compact(dsts)
+
+	var inserted int64
+	for start := 0; start < len(dsts); start += bulkRows {
+		chunk := dsts[start:min(start+bulkRows, len(dsts))]
+		args := make([]interface{}, 0, 3*len(chunk))
+		for _, dst := range chunk {
+			args = append(args, uuid.New().String(), src, dst)
+		}
+		query := fmt.Sprintf(`INSERT INTO %[1]s (pair_id, title_src, title_dst) VALUES %[2]s
+			ON CONFLICT (title_src, title_dst) DO UPDATE
+			SET last_seen = now(), occurrences = %[1]s.occurrences + 1, updated_at = now()
+			RETURNING (xmax = 0)`, p.TableName, valuesList(len(chunk), 3))
+
+		rows, err := p.DB().Query(p.Context(), query, args...)
+		if err != nil {
+			return inserted, fmt.Errorf("❌ lỗi ghi %d cạnh vào %s: %w", len(chunk), p.TableName, err)
+		}
+		for rows.Next() {
+			var isNew bool
+			if err := rows.Scan(&isNew); err != nil {
+				rows.Close()
+				return inserted, err
+			}
+			if isNew {
+				inserted++
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return inserted, err
+		}
+	}
+	return inserted, nil
+}
+
+// GetNeighbors trả về map title_src -> các title_dst cho danh sách nguồn
+func (p *PairsTable) GetNeighbors(srcIDs []string) (map[string][]string, error) {
+	query := fmt.Sprintf(`SELECT title_src, title_dst FROM %s WHERE title_src = ANY($1::text[]::uuid[])`, p.TableName)
+	rows, err := p.DB().Query(p.Context(), query, srcIDs)
+	if err != nil {
+		return nil, fmt.Errorf("❌ lỗi query %s: %w", p.TableName, err)
+	}
+	defer rows.Close()
+
+	neighbors := make(map[string][]string, len(srcIDs))
+	for rows.Next() {
+		var src, dst string
+		if err := rows.Scan(&src, &dst); err != nil {
+			return nil, err
+		}
+		neighbors[src] = append(neighbors[src], dst)
+	}
+	return neighbors, rows.Err()
+}
+
+// ForEachEdge duyệt từng cạnh (wiki, tên nguồn, tên đích) mà không load toàn bộ bảng vào bộ nhớ
+func (p *PairsTable) ForEachEdge(fn func(wiki, src, dst string) error) error {
+	query := fmt.Sprintf(`
+		SELECT s.wiki, s.name, d.name
+		FROM %s p
+		JOIN %s s ON s.title_id = p.title_src
+		JOIN %s d ON d.title_id = p.title_dst`, p.TableName, TitlesTableName, TitlesTableName)
+	rows, err := p.DB().Query(p.Context(), query)
+	if err != nil {
+		return fmt.Errorf("❌ lỗi query %s: %w", p.TableName, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var wiki, src, dst string
+		if err := rows.Scan(&wiki, &src, &dst); err != nil {
+			return err
+		}
+		if err := fn(wiki, src, dst); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// RepointTitle chuyển mọi cạnh của title fromID sang title toID (dùng khi gộp alias).
+// Cạnh trùng với một cạnh sẵn có của toID được gộp vào cạnh đó; gọi qua Tx để cả ba bước cùng commit.
+func (p *PairsTable) RepointTitle(fromID, toID string) error {
+	for _, cols := range [][2]string{{"title_src", "title_dst"}, {"title_dst", "title_src"}} {
+		col, other := cols[0], cols[1]
+		queries := []string{
+			// cộng dồn vào cạnh đã có của toID
+			fmt.Sprintf(`UPDATE %[1]s p SET occurrences = p.occurrences + a.occurrences,
+				first_seen = LEAST(p.first_seen, a.first_seen), last_seen = GREATEST(p.last_seen, a.last_seen),
+				in_lead = COALESCE(p.in_lead, a.in_lead), updated_at = now()
+				FROM %[1]s a WHERE a.%[2]s = $1 AND p.%[2]s = $2 AND p.%[3]s = a.%[3]s`, p.TableName, col, other),
+			fmt.Sprintf(`DELETE FROM %[1]s a USING %[1]s p
+				WHERE a.%[2]s = $1 AND p.%[2]s = $2 AND p.%[3]s = a.%[3]s`, p.TableName, col, other),
+			fmt.Sprintf(`UPDATE %s SET %s = $2, updated_at = now() WHERE %s = $1`, p.TableName, col, col),
+		}
+		for _, query := range queries {
+			if _, err := p.DB().Exec(p.Context(), query, fromID, toID); err != nil {
+				return fmt.Errorf("❌ lỗi chuyển cạnh %s → %s: %w", fromID, toID, err)
+			}
+		}
 	}
+	return nil
 }
@@ -0,0 +1,68 @@
+package tables
+
+import (
+	"fmt"
+	dbclient "wikicrawler/internal/infra/postgresclient"
+	"wikicrawler/internal/model"
+)
+
+const RateLimiterRulesTableName = "rate_limiter_rules"
+
+// RateLimiterRulesTable kế thừa BaseTable, lưu các rule giới hạn request (xem docs/docs.md)
+type RateLimiterRulesTable struct {
+	dbclient.BaseTable
+}
+
+// NewRateLimiterRulesTable khởi tạo table rate_limiter_rules
+func NewRateLimiterRulesTable(client *dbclient.PostgresClient) *RateLimiterRulesTable {
+	return &RateLimiterRulesTable{
+		BaseTable: dbclient.BaseTable{
+			Client:    client,
+			TableName: RateLimiterRulesTableName,
+			Columns: map[string]string{
+				"id":          "SERIAL PRIMARY KEY",
+				"action":      "VARCHAR(50) NOT NULL",
+				"target_type": "VARCHAR(50) NOT NULL",
+				"target":      "VARCHAR(255)", // host khi target_type = 'host'
+				"limit_value": "INT NOT NULL",
+				"time_unit":   "VARCHAR(20) NOT NULL",
+				"description": "TEXT",
+				"created_at":  "TIMESTAMP DEFAULT CURRENT_TIMESTAMP",
+				"updated_at":  "TIMESTAMP DEFAULT CURRENT_TIMESTAMP",
+			},
+		},
+	}
+}
+
+// GetRules trả về toàn bộ rule, theo id
+func (r *RateLimiterRulesTable) GetRules() ([]model.RateLimitRule, error) {
+	query := fmt.Sprintf(`SELECT id, action, target_type, COALESCE(target, ''), limit_value, time_unit
+		FROM %s ORDER BY id`, r.TableName)
+	rows, err := r.DB().Query(r.Context(), query)
+	if err != nil {
+		return nil, fmt.Errorf("❌ lỗi query %s: %w", r.TableName, err)
+	}
+	defer rows.Close()
+
+	var rules []model.RateLimitRule
+	for rows.Next() {
+		var rule model.RateLimitRule
+		if err := rows.Scan(&rule.ID, &rule.Action, &rule.TargetType, &rule.Target, &rule.Limit, &rule.TimeUnit); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// Version trả về checksum của toàn bộ rule; thay đổi khi có rule được thêm, sửa hoặc xóa
+func (r *RateLimiterRulesTable) Version() (string, error) {
+	var sum string
+	query := fmt.Sprintf(`SELECT COALESCE(md5(string_agg(
+			concat_ws('|', id, action, target_type, target, limit_value, time_unit), ',' ORDER BY id)), '')
+		FROM %s`, r.TableName)
+	if err := r.DB().QueryRow(r.Context(), query).Scan(&sum); err != nil {
+		return "", fmt.Errorf("❌ lỗi query %s: %w", r.TableName, err)
+	}
+	return sum, nil
+}
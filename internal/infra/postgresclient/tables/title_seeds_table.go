@@ -0,0 +1,165 @@
+package tables
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	dbclient "wikicrawler/internal/infra/postgresclient"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const TitleSeedsTableName = "title_seeds"
+
+// TitleSeed là số bước ngắn nhất từ một seed tới một title
+type TitleSeed struct {
+	TitleID string
+	Seed    string
+	Depth   int
+}
+
+// TitleSeedsTable kế thừa BaseTable, lưu (title, seed, depth) cho mọi seed tìm
+// thấy title: titles.depth / titles.seed chỉ giữ seed gần nhất
+type TitleSeedsTable struct {
+	dbclient.BaseTable
+}
+
+// NewTitleSeedsTable khởi tạo table title_seeds
+func NewTitleSeedsTable(client *dbclient.PostgresClient) *TitleSeedsTable {
+	return &TitleSeedsTable{
+		BaseTable: dbclient.BaseTable{
+			Client:    client,
+			TableName: TitleSeedsTableName,
+			Columns: map[string]string{
+				"title_id": "UUID NOT NULL",
+				"seed":     "VARCHAR(255) NOT NULL",
+				"depth":    "INT NOT NULL", // hops from seed
+			},
+			Constraints: []string{
+				"PRIMARY KEY (title_id, seed)",
+				"FOREIGN KEY (title_id) REFERENCES titles(title_id) ON DELETE CASCADE",
+				"CREATE INDEX IF NOT EXISTS idx_title_seeds_seed_depth ON title_seeds (seed, depth)",
+			},
+		},
+	}
+}
+
+// Tx trả về bản sao của table chạy trong tx
+func (t *TitleSeedsTable) Tx(ctx context.Context, tx pgx.Tx) *TitleSeedsTable {
+	return &TitleSeedsTable{BaseTable: t.BaseTable.InTx(ctx, tx)}
+}
+
+// UpsertMany ghi các dòng, giữ depth nhỏ nhất cho mỗi (title, seed). Trả về
+// tập title_id có một seed mới hoặc gần hơn trước.
+func (t *TitleSeedsTable) UpsertMany(rows []TitleSeed) (map[string]bool, error) {
+	rows = dedupeSeeds(rows)
+	reached := make(map[string]bool)
+	for start := 0; start < len(rows); start += bulkRows {
+		chunk := rows[start:min(start+bulkRows, len(rows))]
+		args := make([]interface{}, 0, 3*len(chunk))
+		for _, r := range chunk {
+			args = append(args, r.TitleID, r.Seed, r.Depth)
+		}
+		// RETURNING bỏ qua dòng có WHERE của DO UPDATE sai: chỉ còn dòng mới hoặc gần seed hơn
+		query := fmt.Sprintf(`INSERT INTO %[1]s (title_id, seed, depth) VALUES %[2]s
+			ON CONFLICT (title_id, seed) DO UPDATE SET depth = EXCLUDED.depth
+			WHERE %[1]s.depth > EXCLUDED.depth
+			RETURNING title_id`, t.TableName, valuesList(len(chunk), 3))
+
+		res, err := t.DB().Query(t.Context(), query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("❌ lỗi upsert %d dòng vào %s: %w", len(chunk), t.TableName, err)
+		}
+		for res.Next() {
+			var id string
+			if err := res.Scan(&id); err != nil {
+				res.Close()
+				return nil, err
+			}
+			reached[id] = true
+		}
+		res.Close()
+		if err := res.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return reached, nil
+}
+
+// dedupeSeeds giữ một dòng cho mỗi (title_id, seed), dòng có depth nhỏ nhất, đã
+// sắp xếp để mọi transaction khóa theo cùng một thứ tự
+func dedupeSeeds(rows []TitleSeed) []TitleSeed {
+	type key struct{ id, seed string }
+	byKey := make(map[key]TitleSeed, len(rows))
+	for _, r := range rows {
+		k := key{r.TitleID, r.Seed}
+		if prev, ok := byKey[k]; !ok || r.Depth < prev.Depth {
+			byKey[k] = r
+		}
+	}
+	out := make([]TitleSeed, 0, len(byKey))
+	for _, r := range byKey {
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].TitleID != out[j].TitleID {
+			return out[i].TitleID < out[j].TitleID
+		}
+		return out[i].Seed < out[j].Seed
+	})
+	return out
+}
+
+// GetByTitle trả về seed -> depth của một title
+func (t *TitleSeedsTable) GetByTitle(id string) (map[string]int, error) {
+	query := fmt.Sprintf(`SELECT seed, depth FROM %s WHERE title_id = $1`, t.TableName)
+	rows, err := t.DB().Query(t.Context(), query, id)
+	if err != nil {
+		return nil, fmt.Errorf("❌ lỗi query %s: %w", t.TableName, err)
+	}
+	defer rows.Close()
+
+	seeds := make(map[string]int)
+	for rows.Next() {
+		var seed string
+		var depth int
+		if err := rows.Scan(&seed, &depth); err != nil {
+			return nil, err
+		}
+		seeds[seed] = depth
+	}
+	return seeds, rows.Err()
+}
+
+// GetNamesWithinHops trả về các title của wiki cách seed tối đa maxDepth bước, theo thứ tự depth
+func (t *TitleSeedsTable) GetNamesWithinHops(wiki, seed string, maxDepth int) ([]string, error) {
+	query := fmt.Sprintf(`SELECT t.name FROM %s s JOIN %s t USING (title_id)
+		WHERE t.wiki = $1 AND s.seed = $2 AND s.depth <= $3 ORDER BY s.depth, t.name`,
+		t.TableName, TitlesTableName)
+	rows, err := t.DB().Query(t.Context(), query, wiki, seed, maxDepth)
+	if err != nil {
+		return nil, fmt.Errorf("❌ lỗi query %s: %w", t.TableName, err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// Repoint gộp các seed của title fromID vào title toID (dùng khi gộp alias), giữ depth nhỏ nhất
+func (t *TitleSeedsTable) Repoint(fromID, toID string) error {
+	query := fmt.Sprintf(`INSERT INTO %[1]s (title_id, seed, depth)
+		SELECT $2::uuid, seed, depth FROM %[1]s WHERE title_id = $1
+		ON CONFLICT (title_id, seed) DO UPDATE SET depth = LEAST(%[1]s.depth, EXCLUDED.depth)`, t.TableName)
+	if _, err := t.DB().Exec(t.Context(), query, fromID, toID); err != nil {
+		return fmt.Errorf("❌ lỗi gộp seed %s → %s: %w", fromID, toID, err)
+	}
+	return nil
+}
@@ -1,28 +1,193 @@
 package tables
 
-import dbclient "wikicrawler/internal/infra/postgresclient"
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+	dbclient "wikicrawler/internal/infra/postgresclient"
+	"wikicrawler/internal/model"
 
-// TitlesTable kế thừa BaseTable
+	"github.com/jackc/pgx/v5"
+)
+
+const TitlesTableName = "titles"
+
+const (
+	TitleStatusOK      = "ok"
+	TitleStatusMissing = "missing" // MediaWiki has no page with this title (red link, typo)
+	TitleStatusInvalid = "invalid" // not a valid MediaWiki title
+)
+
+// Title là một dòng của bảng titles
+type Title struct {
+	ID        string    `db:"title_id,pk"`
+	Wiki      string    `db:"wiki"`
+	Name      string    `db:"name"`
+	Depth     int       `db:"depth"`
+	Seed      string    `db:"seed"`
+	Status    string    `db:"status,default"`
+	CreatedAt time.Time `db:"created_at,default"`
+	UpdatedAt time.Time `db:"updated_at,default"`
+}
+
+// TitleFromQuery là dòng titles mới cho một title của frontier
+func TitleFromQuery(q model.TitleQuery) Title {
+	return Title{ID: q.ID, Wiki: q.Wiki, Name: q.Title, Depth: q.Depth, Seed: q.Seed}
+}
+
+// TitlesTable kế thừa Repository[Title]
 type TitlesTable struct {
-	dbclient.BaseTable
+	dbclient.Repository[Title]
 }
 
 // NewTitlesTable khởi tạo table entities
 func NewTitlesTable(client *dbclient.PostgresClient) *TitlesTable {
 	return &TitlesTable{
-		BaseTable: dbclient.BaseTable{
+		Repository: dbclient.NewRepository[Title](dbclient.BaseTable{
 			Client:    client,
-			TableName: "titles",
+			TableName: TitlesTableName,
 			Columns: map[string]string{
 				"title_id":   "UUID PRIMARY KEY",
+				"wiki":       "VARCHAR(255) NOT NULL DEFAULT 'en'", // bản ghi cũ đều lấy từ en.wikipedia.org
 				"name":       "VARCHAR(255) NOT NULL",
+				"depth":      "INT NOT NULL DEFAULT 0", // hops from seed
+				"seed":       "VARCHAR(255)",           // seed title it was discovered from
+				"status":     "VARCHAR(16) NOT NULL DEFAULT 'ok'",
 				"created_at": "TIMESTAMP NOT NULL DEFAULT now()",
 				"updated_at": "TIMESTAMP NOT NULL DEFAULT now()",
 			},
 			Constraints: []string{
-				"UNIQUE (name)",
-				"CREATE INDEX IF NOT EXISTS idx_titles_name ON titles (name)",
+				"UNIQUE (wiki, name)",
+				"CHECK (status IN ('ok', 'missing', 'invalid'))",
+				"CREATE INDEX IF NOT EXISTS idx_titles_seed_depth ON titles (wiki, seed, depth)",
 			},
-		},
+		}),
+	}
+}
+
+// GetIDByName trả về title_id của title có (wiki, name) tương ứng
+func (t *TitlesTable) GetIDByName(wiki, name string) (string, error) {
+	var id string
+	query := fmt.Sprintf(`SELECT title_id FROM %s WHERE wiki = $1 AND name = $2`, t.TableName)
+	if err := t.DB().QueryRow(t.Context(), query, wiki, name).Scan(&id); err != nil {
+		return "", fmt.Errorf("❌ không tìm thấy title '%s' (%s): %w", name, wiki, err)
+	}
+	return id, nil
+}
+
+// Tx trả về bản sao của table chạy trong tx
+func (t *TitlesTable) Tx(ctx context.Context, tx pgx.Tx) *TitlesTable {
+	return &TitlesTable{Repository: t.Repository.InTx(ctx, tx)}
+}
+
+// UpsertMany thêm các title (cùng một wiki) bằng INSERT nhiều dòng.
+// Trả về name -> title_id cho mọi title, kể cả title đã có từ trước, và tập
+// các name vừa được thêm mới. Title đã có giữ depth nhỏ nhất (và seed của
+// depth đó); các seed khác nằm trong title_seeds.
+func (t *TitlesTable) UpsertMany(titles []model.TitleQuery) (map[string]string, map[string]bool, error) {
+	titles = closestTitles(titles)
+	ids := make(map[string]string, len(titles))
+	inserted := make(map[string]bool)
+	for start := 0; start < len(titles); start += bulkRows {
+		chunk := titles[start:min(start+bulkRows, len(titles))]
+		args := make([]interface{}, 0, 5*len(chunk))
+		for _, q := range chunk {
+			args = append(args, q.ID, q.Wiki, q.Title, q.Depth, q.Seed)
+		}
+		// xmax = 0 chỉ đúng với dòng vừa INSERT, dòng đi qua DO UPDATE có xmax khác 0
+		query := fmt.Sprintf(`INSERT INTO %[1]s (title_id, wiki, name, depth, seed) VALUES %[2]s
+			ON CONFLICT (wiki, name) DO UPDATE SET depth = LEAST(%[1]s.depth, EXCLUDED.depth),
+				seed = CASE WHEN EXCLUDED.depth < %[1]s.depth THEN EXCLUDED.seed ELSE %[1]s.seed END,
+				updated_at = now()
+			RETURNING title_id, name, (xmax = 0)`, t.TableName, valuesList(len(chunk), 5))
+
+		rows, err := t.DB().Query(t.Context(), query, args...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("❌ lỗi upsert %d title vào %s: %w", len(chunk), t.TableName, err)
+		}
+		for rows.Next() {
+			var id, name string
+			var isNew bool
+			if err := rows.Scan(&id, &name, &isNew); err != nil {
+				rows.Close()
+				return nil, nil, err
+			}
+			ids[name] = id
+			if isNew {
+				inserted[name] = true
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, nil, err
+		}
+	}
+	return ids, inserted, nil
+}
+
+// closestTitles giữ một dòng cho mỗi name, dòng có depth nhỏ nhất (DO UPDATE
+// không được chạm một dòng hai lần), sắp theo name để mọi transaction khóa
+// theo cùng một thứ tự
+func closestTitles(titles []model.TitleQuery) []model.TitleQuery {
+	byName := make(map[string]model.TitleQuery, len(titles))
+	for _, q := range titles {
+		if prev, ok := byName[q.Title]; !ok || q.Depth < prev.Depth {
+			byName[q.Title] = q
+		}
+	}
+	out := make([]model.TitleQuery, 0, len(byName))
+	for _, q := range byName {
+		out = append(out, q)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Title < out[j].Title })
+	return out
+}
+
+// GetNamesByIDs trả về map title_id -> name cho danh sách id
+func (t *TitlesTable) GetNamesByIDs(ids []string) (map[string]string, error) {
+	names := make(map[string]string, len(ids))
+	for title, err := range t.Find(t.Select().Where("title_id", "IN", ids)) {
+		if err != nil {
+			return nil, err
+		}
+		names[title.ID] = title.Name
+	}
+	return names, nil
+}
+
+// DeleteByID xóa một title theo title_id
+func (t *TitlesTable) DeleteByID(id string) error {
+	_, err := t.Delete("title_id", id)
+	return err
+}
+
+// SetStatus đánh dấu title là ok / missing / invalid
+func (t *TitlesTable) SetStatus(id, status string) error {
+	query := fmt.Sprintf(`UPDATE %s SET status = $2, updated_at = now() WHERE title_id = $1`, t.TableName)
+	if _, err := t.DB().Exec(t.Context(), query, id, status); err != nil {
+		return fmt.Errorf("❌ lỗi cập nhật status title %s: %w", id, err)
+	}
+	return nil
+}
+
+// CountByStatus đếm số title theo từng status
+func (t *TitlesTable) CountByStatus() (map[string]int64, error) {
+	query := fmt.Sprintf(`SELECT status, COUNT(*) FROM %s GROUP BY status`, t.TableName)
+	rows, err := t.DB().Query(t.Context(), query)
+	if err != nil {
+		return nil, fmt.Errorf("❌ lỗi đếm %s: %w", t.TableName, err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var status string
+		var n int64
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
 	}
+	return counts, rows.Err()
 }
@@ -0,0 +1,188 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	dbclient "wikicrawler/internal/infra/postgresclient"
+	"wikicrawler/internal/infra/postgresclient/tables"
+	"wikicrawler/internal/infra/redisclient"
+	"wikicrawler/internal/model"
+	"wikicrawler/internal/utils/processor"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RulesLimiter applies the rules of the rate_limiter_rules table to outgoing
+// requests. Every rule is a token bucket kept in Redis under prefix:<rule id>,
+// so all crawler processes draw from the same budget. The rules are reloaded
+// whenever the table changes.
+type RulesLimiter struct {
+	processor.BaseProcessor
+	table          *tables.RateLimiterRulesTable
+	client         *redis.Client
+	prefix         string
+	reloadInterval time.Duration
+	ctx            context.Context
+
+	mu      sync.RWMutex
+	rules   []model.RateLimitRule
+	version string
+}
+
+func NewRulesLimiter(db *dbclient.PostgresClient, rc *redisclient.RedisClient, prefix string,
+	reloadInterval time.Duration) (*RulesLimiter, error) {
+	l := &RulesLimiter{
+		table:          tables.NewRateLimiterRulesTable(db),
+		client:         rc.GetClient(),
+		prefix:         prefix,
+		reloadInterval: reloadInterval,
+		ctx:            context.Background(),
+	}
+	if err := l.reload(); err != nil {
+		return nil, err
+	}
+	l.Init(l)
+	return l, nil
+}
+
+// RunningTask reloads the rules when the table changed.
+func (l *RulesLimiter) RunningTask() {
+	time.Sleep(l.reloadInterval)
+	if err := l.reload(); err != nil {
+		fmt.Printf("[RulesLimiter] Failed to reload rules, keeping the old ones: %v\n", err)
+	}
+}
+
+func (l *RulesLimiter) reload() error {
+	version, err := l.table.Version()
+	if err != nil {
+		return err
+	}
+	l.mu.RLock()
+	unchanged := version == l.version
+	l.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	rules, err := l.table.GetRules()
+	if err != nil {
+		return err
+	}
+	var valid []model.RateLimitRule
+	for _, r := range rules {
+		if _, err := period(r.TimeUnit); err != nil || r.Limit <= 0 {
+			fmt.Printf("[RulesLimiter] Skipping rule %d: limit %d per %q\n", r.ID, r.Limit, r.TimeUnit)
+			continue
+		}
+		valid = append(valid, r)
+	}
+
+	l.mu.Lock()
+	l.rules, l.version = valid, version
+	l.mu.Unlock()
+	fmt.Printf("[RulesLimiter] %d rate limit rule(s) loaded\n", len(valid))
+	return nil
+}
+
+// Rules returns the rules in effect.
+func (l *RulesLimiter) Rules() []model.RateLimitRule {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]model.RateLimitRule(nil), l.rules...)
+}
+
+// matching returns the rules that apply to action on host.
+func (l *RulesLimiter) matching(host, action string) []model.RateLimitRule {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var out []model.RateLimitRule
+	for _, r := range l.rules {
+		if r.Action != "*" && !strings.EqualFold(r.Action, action) {
+			continue
+		}
+		switch r.TargetType {
+		case "global":
+		case "host":
+			if !strings.EqualFold(r.Target, host) {
+				continue
+			}
+		default:
+			continue // user, ip, post, ... are not about crawling
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// KEYS: one bucket per rule  ARGV: (limit, period_ms) per key
+// Takes one token from every bucket, or none; returns 0 or the ms to wait.
+var takeScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local wait = 0
+local tokens = {}
+for i, key in ipairs(KEYS) do
+	local limit, period = tonumber(ARGV[2*i-1]), tonumber(ARGV[2*i])
+	local b = redis.call('HMGET', key, 'tokens', 'ts')
+	local have = tonumber(b[1]) or limit
+	local ts = tonumber(b[2]) or now
+	have = math.min(limit, have + (now - ts) * limit / period)
+	tokens[i] = have
+	if have < 1 then
+		wait = math.max(wait, math.ceil((1 - have) * period / limit))
+	end
+end
+if wait > 0 then
+	return wait
+end
+for i, key in ipairs(KEYS) do
+	redis.call('HSET', key, 'tokens', tostring(tokens[i] - 1), 'ts', tostring(now))
+	redis.call('PEXPIRE', key, tonumber(ARGV[2*i]) * 2)
+end
+return 0
+`)
+
+// Wait blocks until every rule matching action on host allows one more request.
+func (l *RulesLimiter) Wait(host, action string) error {
+	rules := l.matching(host, action)
+	if len(rules) == 0 {
+		return nil
+	}
+	keys := make([]string, len(rules))
+	args := make([]interface{}, 0, 2*len(rules))
+	for i, r := range rules {
+		p, _ := period(r.TimeUnit)
+		keys[i] = l.prefix + ":" + strconv.Itoa(r.ID)
+		args = append(args, r.Limit, p.Milliseconds())
+	}
+
+	for {
+		wait, err := takeScript.Run(l.ctx, l.client, keys, args...).Int64()
+		if err != nil {
+			return fmt.Errorf("[RulesLimiter] failed to take a token for %s %s: %w", action, host, err)
+		}
+		if wait == 0 {
+			return nil
+		}
+		time.Sleep(time.Duration(wait) * time.Millisecond)
+	}
+}
+
+func period(unit string) (time.Duration, error) {
+	switch strings.ToLower(unit) {
+	case "second":
+		return time.Second, nil
+	case "minute":
+		return time.Minute, nil
+	case "hour":
+		return time.Hour, nil
+	case "day":
+		return 24 * time.Hour, nil
+	}
+	return 0, fmt.Errorf("unknown time unit %q", unit)
+}
@@ -1,57 +1,134 @@
 package infra
 
 import (
+	"context"
 	"fmt"
+	"strings"
+	"time"
+	"wikicrawler/internal/infra/frontier"
+	"wikicrawler/internal/infra/graphstore"
 	dbclient "wikicrawler/internal/infra/postgresclient"
-	"wikicrawler/internal/infra/postgresclient/tables"
+	"wikicrawler/internal/infra/postgresclient/migrations"
 	"wikicrawler/internal/infra/redisclient"
 	"wikicrawler/internal/model"
 	"wikicrawler/internal/utils/file"
 )
 
 type WikiStore struct {
-	TitlsToQueryQ chan model.TitleQuery
-	RawDataQ      chan model.RawDataWiki
-	DBclient      *dbclient.PostgresClient
-	RedisClient   *redisclient.RedisClient
-	TitlesTable   *tables.TitlesTable
-	PairsTable    *tables.PairsTable
+	Frontier    frontier.Frontier
+	RawDataQ    chan model.RawDataWiki
+	DBclient    *dbclient.PostgresClient
+	RedisClient *redisclient.RedisClient
+	Graph       graphstore.GraphStore
 }
 
-func NewWikiStore(datapath string, cfg dbclient.PostGresConfig, rcfg redisclient.RedisConfig, RawDataQCap, TitlsToQueryQCap int) *WikiStore {
+// NewWikiStore connects to Postgres and makes sure the graph tables exist.
+// Redis, the frontier and the seed titles are only needed by the crawler, see
+// ConnectRedis, OpenFrontier and LoadSeeds.
+func NewWikiStore(cfg dbclient.PostGresConfig, RawDataQCap int) (*WikiStore, error) {
 	w := &WikiStore{}
-	db := dbclient.NewPostgresClient(cfg)
+	db, err := dbclient.NewPostgresClient(context.Background(), cfg)
+	if err != nil {
+		return nil, fmt.Errorf("[WikiStore] %w", err)
+	}
 	w.DBclient = db
-	w.PairsTable = tables.NewPairsTable(db)
-	w.TitlesTable = tables.NewTitlesTable(db)
-	if !db.SearchTable(w.TitlesTable.TableName) {
-		fmt.Printf("%s NOT EXIST - CREATION PROCESS STARTING\n", w.TitlesTable.TableName)
-		w.TitlesTable.CreateTable()
-	} else {
-		fmt.Printf("%s EXISTED\n", w.TitlesTable.TableName)
+	if err := w.EnsureTables(); err != nil {
+		db.Close()
+		return nil, err
 	}
+	w.Graph = graphstore.NewPostgresGraphStore(db)
+
+	w.RawDataQ = make(chan model.RawDataWiki, RawDataQCap)
+	return w, nil
+}
 
-	if !db.SearchTable(w.PairsTable.TableName) {
-		fmt.Printf("%s NOT EXIST - CREATION PROCESS STARTING\n", w.PairsTable.TableName)
-		w.PairsTable.CreateTable()
-	} else {
-		fmt.Printf("%s EXISTED\n", w.PairsTable.TableName)
+// EnsureTables migrates the schema to the latest version (see package
+// migrations), so every process runs against the tables it was built for.
+func (w *WikiStore) EnsureTables() error {
+	m, err := migrations.NewMigrator(w.DBclient, migrations.All)
+	if err == nil {
+		_, err = m.Up(0)
+	}
+	if err != nil {
+		return fmt.Errorf("[WikiStore] failed to migrate the schema: %w", err)
 	}
+	return nil
+}
 
+func (w *WikiStore) ConnectRedis(rcfg redisclient.RedisConfig) {
 	w.RedisClient = redisclient.InitSingleton(rcfg)
-	w.TitlsToQueryQ = make(chan model.TitleQuery, TitlsToQueryQCap)
-
-	if titles, err := file.ReadTextFile(datapath); err == nil {
-		fmt.Println(titles)
-		for _, e := range titles {
-			w.TitlsToQueryQ <- model.TitleQuery{
-				Title: e,
-			}
+}
+
+// OpenFrontier selects the durable crawl frontier: "postgres" or "redis"
+// (the latter needs ConnectRedis first).
+func (w *WikiStore) OpenFrontier(backend string, lease time.Duration) error {
+	switch backend {
+	case "postgres":
+		w.Frontier = frontier.NewPostgresFrontier(w.DBclient, lease)
+	case "redis":
+		if w.RedisClient == nil {
+			return fmt.Errorf("[WikiStore] redis frontier needs a redis connection")
 		}
-	} else {
+		w.Frontier = frontier.NewRedisFrontier(w.RedisClient, "wikicrawler:frontier", lease)
+	default:
+		return fmt.Errorf("[WikiStore] unknown frontier backend %q", backend)
+	}
+	return nil
+}
+
+// LoadSeeds pushes every title listed in the seed file to the frontier.
+// Titles already known to the frontier are left untouched, so this is safe on every start.
+func (w *WikiStore) LoadSeeds(datapath, defaultWiki string) {
+	titles, err := file.ReadTextFile(datapath)
+	if err != nil {
 		fmt.Printf("[WikiStore] Failed to read seed names from %s: %v\n", datapath, err)
+		return
+	}
+	if _, err := w.PushSeeds(titles, defaultWiki); err != nil {
+		fmt.Printf("[WikiStore] Failed to push seeds: %v\n", err)
 	}
+}
 
-	w.RawDataQ = make(chan model.RawDataWiki, RawDataQCap)
-	return w
+// PushSeeds adds titles at depth 0 to the frontier and returns how many were new.
+// A title may name its wiki with an interlanguage prefix ("en:Donald Trump"),
+// otherwise it belongs to defaultWiki.
+func (w *WikiStore) PushSeeds(titles []string, defaultWiki string) (int, error) {
+	var seeds []model.TitleQuery
+	for _, e := range titles {
+		wiki, title := SplitWikiPrefix(strings.TrimSpace(e), defaultWiki)
+		if title == "" {
+			continue
+		}
+		seeds = append(seeds, model.TitleQuery{
+			Wiki:  wiki,
+			Title: title,
+			Seed:  title,
+		})
+	}
+	n, err := w.Frontier.Push(seeds...)
+	fmt.Printf("[WikiStore] %d of %d seed(s) added to the frontier\n", n, len(seeds))
+	return n, err
+}
+
+// SplitWikiPrefix splits "vi:Sơn Tùng M-TP" into ("vi", "Sơn Tùng M-TP"). Only a
+// lowercase language code counts as a prefix: titles start with an uppercase
+// letter, so "Star Wars: Episode IV" or "Category:X" are left untouched.
+func SplitWikiPrefix(s, defaultWiki string) (string, string) {
+	prefix, rest, ok := strings.Cut(s, ":")
+	if !ok || prefix == "" || len(prefix) > 12 {
+		return defaultWiki, s
+	}
+	for _, r := range prefix {
+		if (r < 'a' || r > 'z') && r != '-' {
+			return defaultWiki, s
+		}
+	}
+	return prefix, strings.TrimSpace(rest)
+}
+
+func (w *WikiStore) Close() {
+	if w.RedisClient != nil {
+		w.RedisClient.Close()
+	}
+	w.DBclient.Close()
 }
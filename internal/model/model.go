@@ -1,39 +1,130 @@
 package model
 
+import "github.com/google/uuid"
+
 type WikiLinksResponse struct {
 	Continue struct {
 		Plcontinue string `json:"plcontinue"` //Pagination: Token to fetch next batch of links
 		Continue   string `json:"continue"`   //Generic pagination token (present if more results)
 	} `json:"continue"`
 	Query struct {
-		Normalized []struct {
-			From string `json:"from"`
-			To   string `json:"to"` //Normalized wikipedia title (Use this in BFS to avoid duplicate nodes)
-		} `json:"normalized"`
+		Normalized []Normalization `json:"normalized"`
+		Redirects  []Redirect      `json:"redirects"` // only with redirects=1
 		//Pages is a dynamic map. Key: pageid Value: a wiki page
-		Pages map[string]struct {
-			Pageid int    `json:"pageid"` //Wiki page ID (unique for each page)
-			Ns     int    `json:"ns"`     // Namespace ID (always 0 here, kept for completeness)
-			Title  string `json:"title"`
-			Links  []struct {
-				Ns    int    `json:"ns"`
-				Title string `json:"title"`
-			} `json:"links"`
-		} `json:"pages"`
+		Pages map[string]WikiPage `json:"pages"`
 	} `json:"query"`
 	Limits struct {
 		Links int `json:"links"` //Max links returned per page
 	} `json:"limits"`
+	Error    *APIErrorInfo         `json:"error,omitempty"`    // request rejected, answered with HTTP 200
+	Warnings map[string]APIWarning `json:"warnings,omitempty"` // key: module that warned, e.g. "main", "links"
+}
+
+type APIErrorInfo struct {
+	Code string `json:"code"` // e.g. "maxlag", "toomanyvalues", "badcontinue"
+	Info string `json:"info"`
+}
+
+type APIWarning struct {
+	Text string `json:"*"` // formatversion=1 puts the message under "*"
+}
+
+// Flag is a MediaWiki boolean: formatversion=1 marks it with an empty string
+// ("missing": ""), formatversion=2 with true. Any value but false sets it.
+type Flag bool
+
+func (f *Flag) UnmarshalJSON(b []byte) error {
+	*f = string(b) != "false" && string(b) != "null"
+	return nil
+}
+
+type Normalization struct {
+	From string `json:"from"`
+	To   string `json:"to"` //Normalized wikipedia title (Use this in BFS to avoid duplicate nodes)
+}
+
+type Redirect struct {
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Tofragment string `json:"tofragment,omitempty"` // section anchor of the target, if any
+}
+
+// Canonical follows normalization then redirects from a requested title to the
+// title of the page MediaWiki actually answered with.
+func (r *WikiLinksResponse) Canonical(title string) string {
+	for _, n := range r.Query.Normalized {
+		if n.From == title {
+			title = n.To
+			break
+		}
+	}
+	// Redirect chains are resolved by the API; guard against loops anyway
+	for i := 0; i < len(r.Query.Redirects); i++ {
+		next := title
+		for _, rd := range r.Query.Redirects {
+			if rd.From == title {
+				next = rd.To
+				break
+			}
+		}
+		if next == title {
+			break
+		}
+		title = next
+	}
+	return title
 }
 
+type WikiPage struct {
+	Pageid int        `json:"pageid"` //Wiki page ID (unique for each page)
+	Ns     int        `json:"ns"`     // Namespace ID (always 0 here, kept for completeness)
+	Title  string     `json:"title"`
+	Links  []WikiLink `json:"links"`
+
+	Missing       Flag   `json:"missing,omitempty"` // no page with this title
+	Invalid       Flag   `json:"invalid,omitempty"` // not a valid title at all
+	InvalidReason string `json:"invalidreason,omitempty"`
+}
+
+type WikiLink struct {
+	Ns    int    `json:"ns"`
+	Title string `json:"title"`
+}
+
+// RawDataWiki is every link of one queried title; LinksRes holds that title's page only.
 type RawDataWiki struct {
-	TitleQ   TitleQuery
+	TitleQ   TitleQuery // as leased: the frontier acks it under this key
+	Wiki     string     // wiki it was fetched from; TitleQ.Wiki is empty for titles queued without one
 	LinksRes WikiLinksResponse
+	Err      error // set when the title has no page (see api.TitleError)
 }
 
 type TitleQuery struct {
+	Wiki  string // language code, host or api.php URL the title belongs to, e.g. "vi"
 	Title string
 	ID    string
+	Depth int    // hops from Seed, 0 for seed titles
+	Seed  string // seed title this one was discovered from
+}
+
+// Child returns the query for a title linked from q, one hop further from the same seed.
+func (q TitleQuery) Child(title string) TitleQuery {
+	return TitleQuery{Wiki: q.Wiki, Title: title, ID: TitleID(q.Wiki, title), Depth: q.Depth + 1, Seed: q.Seed}
+}
+
+// TitleNamespace is the UUIDv5 namespace of title IDs. Never change it: every
+// stored ID, edge and Kafka event refers to IDs derived from it.
+var TitleNamespace = uuid.MustParse("5b0e2f4c-8f4e-4c1a-9d3e-6a7f1c2b9e10")
+
+// TitleID is the deterministic ID of a canonical title: the UUIDv5 of
+// "wiki|title", the same in every database, re-import and consumer.
+func TitleID(wiki, title string) string {
+	return uuid.NewSHA1(TitleNamespace, []byte(wiki+"|"+title)).String()
+}
+
+// Key identifies a title across wikis; '|' cannot appear in MediaWiki titles.
+func (q TitleQuery) Key() string {
+	return q.Wiki + "|" + q.Title
 }
 
 type TitlesPair struct {
@@ -47,3 +138,14 @@ type DBEvent struct {
 	CMD    string      `json:"cmd"`
 	Data   interface{} `json:"data"`
 }
+
+// RateLimitRule is one row of rate_limiter_rules: at most Limit requests per
+// TimeUnit for Action ("query", or "*" for any) to Target.
+type RateLimitRule struct {
+	ID         int
+	Action     string
+	TargetType string // "global" (every host) or "host"
+	Target     string // host name when TargetType is "host"
+	Limit      int
+	TimeUnit   string // "second", "minute", "hour" or "day"
+}
@@ -27,3 +27,27 @@ func ReadTextFile(filePath string) ([]string, error) {
 
 	return lines, nil
 }
+
+// AppendLines appends each line to a .txt file, creating it if needed.
+func AppendLines(filePath string, lines []string) error {
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	// Keep the last existing line intact if the file has no trailing newline
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			w.WriteString("\n")
+		}
+	}
+	for _, line := range lines {
+		if _, err := w.WriteString(line + "\n"); err != nil {
+			return fmt.Errorf("error writing file %s: %w", filePath, err)
+		}
+	}
+	return w.Flush()
+}
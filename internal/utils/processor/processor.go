@@ -1,6 +1,7 @@
 package processor
 
 import (
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -14,7 +15,8 @@ type Processor interface {
 
 type BaseProcessor struct {
 	processor Processor
-	running   atomic.Bool // true if running
+	running   atomic.Bool    // true if running
+	loop      sync.WaitGroup // the RunningTask loop of Start, until it sees Stop
 }
 
 func (b *BaseProcessor) Init(p Processor) {
@@ -24,7 +26,9 @@ func (b *BaseProcessor) Init(p Processor) {
 func (b *BaseProcessor) Start() error {
 	b.running.Store(true)
 
+	b.loop.Add(1)
 	go func() {
+		defer b.loop.Done()
 		for b.running.Load() {
 			b.processor.RunningTask()
 		}
@@ -37,6 +41,14 @@ func (b *BaseProcessor) Stop() error {
 	return nil
 }
 
+// Wait blocks until the loop started by Start has returned, i.e. the
+// RunningTask in progress when Stop was called is over. Stop does not wait, so
+// a processor that must not run a task after it released something (a file,
+// a connection) calls Wait in between.
+func (b *BaseProcessor) Wait() {
+	b.loop.Wait()
+}
+
 func (b *BaseProcessor) Restart() error {
 	if err := b.Stop(); err != nil {
 		return err
@@ -0,0 +1,38 @@
+package processor
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type slowProcessor struct {
+	BaseProcessor
+	inTask atomic.Bool
+	runs   atomic.Int32
+}
+
+func (p *slowProcessor) RunningTask() {
+	p.inTask.Store(true)
+	time.Sleep(20 * time.Millisecond)
+	p.runs.Add(1)
+	p.inTask.Store(false)
+}
+
+func TestWaitReturnsOnceTheTaskInProgressIsOver(t *testing.T) {
+	p := &slowProcessor{}
+	p.Init(p)
+	p.Start()
+	time.Sleep(5 * time.Millisecond)
+
+	p.Stop()
+	p.Wait()
+	if p.inTask.Load() {
+		t.Fatal("Wait returned while RunningTask was still running")
+	}
+	runs := p.runs.Load()
+	time.Sleep(50 * time.Millisecond)
+	if got := p.runs.Load(); got != runs {
+		t.Errorf("%d task(s) ran after Wait", got-runs)
+	}
+}
@@ -0,0 +1,120 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// Policy decides whether a failed attempt is tried again and how long to wait first.
+type Policy interface {
+	// Backoff is called after attempt (1-based) failed with err. It returns the
+	// wait before the next attempt, or false to give up.
+	Backoff(attempt int, err error) (time.Duration, bool)
+}
+
+// permanentError marks an error that no retry will fix.
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so that Do gives up at once.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err, or an error it wraps, was marked Permanent.
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// hintError carries the wait the server asked for (Retry-After).
+type hintError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *hintError) Error() string { return e.err.Error() }
+func (e *hintError) Unwrap() error { return e.err }
+
+// After wraps a retryable err with the wait the server asked for.
+func After(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &hintError{err: err, after: d}
+}
+
+// RetryAfter returns the wait attached by After, if any.
+func RetryAfter(err error) (time.Duration, bool) {
+	var h *hintError
+	if errors.As(err, &h) {
+		return h.after, true
+	}
+	return 0, false
+}
+
+// ExhaustedError is returned by Do when the policy gave up on a retryable error.
+type ExhaustedError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *ExhaustedError) Error() string {
+	return fmt.Sprintf("gave up after %d attempt(s): %v", e.Attempts, e.Err)
+}
+
+func (e *ExhaustedError) Unwrap() error { return e.Err }
+
+// Do runs fn until it succeeds, returns a Permanent error, or p gives up.
+func Do(p Policy, fn func(attempt int) error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn(attempt)
+		if err == nil {
+			return nil
+		}
+		if IsPermanent(err) {
+			return err
+		}
+		wait, ok := p.Backoff(attempt, err)
+		if !ok {
+			return &ExhaustedError{Attempts: attempt, Err: err}
+		}
+		time.Sleep(wait)
+	}
+}
+
+// Exponential doubles the wait from Base up to Max, for at most MaxAttempts
+// attempts. A Retry-After hint longer than the computed wait is honored.
+// Jitter (0..1) is the fraction of every wait that is randomized, so workers
+// that failed together do not retry together.
+type Exponential struct {
+	Base        time.Duration
+	Max         time.Duration
+	MaxAttempts int
+	Jitter      float64
+}
+
+func (e Exponential) Backoff(attempt int, err error) (time.Duration, bool) {
+	if attempt >= e.MaxAttempts {
+		return 0, false
+	}
+	wait := e.Base << min(attempt-1, 30)
+	if wait <= 0 || (e.Max > 0 && wait > e.Max) {
+		wait = e.Max
+	}
+	if e.Jitter > 0 {
+		spread := time.Duration(float64(wait) * min(e.Jitter, 1))
+		wait = wait - spread + rand.N(spread+1)
+	}
+	if hint, ok := RetryAfter(err); ok && hint > wait {
+		wait = hint
+	}
+	return wait, true
+}
@@ -0,0 +1,145 @@
+package spill
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Queue is a FIFO of JSON lines in one file, for items that do not fit in
+// memory. The file is truncated whenever the queue runs empty. How far it was
+// read is kept in path + ".offset", so after Open only the items that were
+// never popped come out again: an item popped just before the process stops is
+// not delivered twice.
+type Queue[T any] struct {
+	mu     sync.Mutex
+	w      *os.File
+	r      *os.File
+	br     *bufio.Reader
+	off    *os.File // read offset of r, see saveOffset
+	offset int64
+	size   int64
+}
+
+func Open[T any](path string) (*Queue[T], error) {
+	w, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("[Spill] failed to open %s: %w", path, err)
+	}
+	r, err := os.Open(path)
+	if err != nil {
+		w.Close()
+		return nil, fmt.Errorf("[Spill] failed to open %s: %w", path, err)
+	}
+
+	off, err := os.OpenFile(path+".offset", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		w.Close()
+		r.Close()
+		return nil, fmt.Errorf("[Spill] failed to open %s.offset: %w", path, err)
+	}
+
+	q := &Queue[T]{w: w, r: r, br: bufio.NewReaderSize(r, 1<<20), off: off}
+	// Left over from the last run, minus what it popped already
+	data, err := os.ReadFile(path)
+	if err != nil {
+		q.Close()
+		return nil, err
+	}
+	saved, err := io.ReadAll(off)
+	if err != nil {
+		q.Close()
+		return nil, fmt.Errorf("[Spill] failed to read %s.offset: %w", path, err)
+	}
+	if n, err := strconv.ParseInt(strings.TrimSpace(string(saved)), 10, 64); err == nil && n > 0 && n <= int64(len(data)) {
+		q.offset = n
+	}
+	if _, err := r.Seek(q.offset, io.SeekStart); err != nil {
+		q.Close()
+		return nil, fmt.Errorf("[Spill] failed to seek %s: %w", path, err)
+	}
+	q.size = int64(bytes.Count(data[q.offset:], []byte("\n")))
+	return q, nil
+}
+
+// Push appends v to the queue.
+func (q *Queue[T]) Push(v T) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("[Spill] failed to encode item: %w", err)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, err := q.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("[Spill] failed to write item: %w", err)
+	}
+	q.size++
+	return nil
+}
+
+// Pop removes the oldest item; ok is false when the queue is empty.
+func (q *Queue[T]) Pop() (v T, ok bool, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.size == 0 {
+		return v, false, nil
+	}
+	line, err := q.br.ReadBytes('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && len(line) > 0) {
+		return v, false, fmt.Errorf("[Spill] failed to read item: %w", err)
+	}
+	q.size--
+	q.offset += int64(len(line))
+	if q.size == 0 {
+		err = q.reset()
+	} else {
+		err = q.saveOffset()
+	}
+	if err != nil {
+		return v, false, err
+	}
+	if err := json.Unmarshal(line, &v); err != nil {
+		return v, false, fmt.Errorf("[Spill] failed to decode item: %w", err)
+	}
+	return v, true, nil
+}
+
+// reset empties the file once every item was read; q.mu is held.
+func (q *Queue[T]) reset() error {
+	if err := q.w.Truncate(0); err != nil {
+		return fmt.Errorf("[Spill] failed to truncate: %w", err)
+	}
+	if _, err := q.r.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("[Spill] failed to rewind: %w", err)
+	}
+	q.br.Reset(q.r)
+	q.offset = 0
+	return q.saveOffset()
+}
+
+// saveOffset records q.offset before the item is handed out; q.mu is held.
+// A fixed width overwrites the previous value in one write.
+func (q *Queue[T]) saveOffset() error {
+	if _, err := q.off.WriteAt([]byte(fmt.Sprintf("%020d\n", q.offset)), 0); err != nil {
+		return fmt.Errorf("[Spill] failed to save offset: %w", err)
+	}
+	return nil
+}
+
+// Len returns the number of items in the queue.
+func (q *Queue[T]) Len() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.size
+}
+
+func (q *Queue[T]) Close() error {
+	return errors.Join(q.w.Close(), q.r.Close(), q.off.Close())
+}
@@ -0,0 +1,61 @@
+package spill
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func open(t *testing.T, path string) *Queue[int] {
+	t.Helper()
+	q, err := Open[int](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func pop(t *testing.T, q *Queue[int]) int {
+	t.Helper()
+	v, ok, err := q.Pop()
+	if err != nil || !ok {
+		t.Fatalf("Pop = %d, %v, %v", v, ok, err)
+	}
+	return v
+}
+
+func TestPoppedItemsAreNotRedeliveredAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spill.jsonl")
+	q := open(t, path)
+	for i := 1; i <= 3; i++ {
+		if err := q.Push(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if v := pop(t, q); v != 1 {
+		t.Fatalf("Pop = %d, want 1", v)
+	}
+	q.Close() // stops without draining the file
+
+	q = open(t, path)
+	if n := q.Len(); n != 2 {
+		t.Fatalf("Len after restart = %d, want 2", n)
+	}
+	q.Push(4)
+	for _, want := range []int{2, 3, 4} {
+		if v := pop(t, q); v != want {
+			t.Fatalf("Pop = %d, want %d", v, want)
+		}
+	}
+	q.Close()
+
+	// Emptied: nothing left, and the truncated file starts from 0 again
+	q = open(t, path)
+	defer q.Close()
+	if n := q.Len(); n != 0 {
+		t.Fatalf("Len of drained queue = %d", n)
+	}
+	q.Push(5)
+	if v := pop(t, q); v != 5 {
+		t.Fatalf("Pop = %d, want 5", v)
+	}
+}
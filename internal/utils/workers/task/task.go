@@ -53,6 +53,22 @@ func (q *TaskQueue) Push(t *Task) bool {
 	}
 }
 
+// PushTimeout waits up to timeout for room in the queue; timeout <= 0 waits as long as it takes.
+func (q *TaskQueue) PushTimeout(t *Task, timeout time.Duration) bool {
+	if timeout <= 0 {
+		q.Tasks <- t
+		return true
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case q.Tasks <- t:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
 func (q *TaskQueue) TryPop(timeout time.Duration) *Task {
 	select {
 	case t := <-q.Tasks: